@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// alertFooter is how many of the latest alert lines stay in a footer below
+// the view (--alert-footer). Lines are alerts when they match a rule with the
+// alert option, as in "fatal = red, alert".
+var alertFooter int
+
+// recentAlerts holds copies of the latest alert lines, oldest first. Guarded
+// by logsMutex.
+var recentAlerts []LogEntry
+
+// isAlert reports whether a line matches any alert rule.
+func isAlert(cfg Config, line string) bool {
+	for _, rule := range cfg.Highlights {
+		if rule.Alert && rule.matches(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// bell rings the terminal bell when an alert rule fires (--bell).
+var bell bool
+
+// alertFired is when each alert rule last fired, for its cooldown. Guarded by
+// logsMutex.
+var alertFired = make(map[string]time.Time)
+
+// firedAlerts returns the alert rules an entry matches that aren't cooling
+// down, and marks them as fired. It also reports whether the entry matches
+// any alert rule, cooling down or not. The caller must hold logsMutex for
+// writing.
+func firedAlerts(cfg Config, entry LogEntry) ([]HighlightRule, bool) {
+	var fired []HighlightRule
+	matched := false
+	for _, rule := range cfg.Highlights {
+		if !rule.Alert || !rule.matches(entry.Text) {
+			continue
+		}
+		matched = true
+		if last, ok := alertFired[rule.Word]; ok && entry.Arrived.Sub(last) < rule.Cooldown {
+			continue
+		}
+		alertFired[rule.Word] = entry.Arrived
+		fired = append(fired, rule)
+	}
+	return fired, matched
+}
+
+// reactToAlerts runs the reactions to an entry's alerts: the footer, events,
+// and the bell. Every alert goes in the footer, as refreshAlerts would put
+// it, but rules with a cooldown send events and ring at most once per
+// cooldown. The caller must hold logsMutex for writing.
+func reactToAlerts(cfg Config, entry LogEntry) {
+	fired, matched := firedAlerts(cfg, entry)
+	if matched {
+		trackAlert(entry)
+	}
+	if len(fired) == 0 {
+		return
+	}
+	emitEvents(fired, entry)
+	if bell && isTerminal(os.Stdout) {
+		os.Stdout.WriteString("\a")
+	}
+}
+
+// trackAlert keeps an alert entry in the footer. The caller must hold
+// logsMutex for writing.
+func trackAlert(entry LogEntry) {
+	if alertFooter <= 0 {
+		return
+	}
+	recentAlerts = append(recentAlerts, entry)
+	if len(recentAlerts) > alertFooter {
+		recentAlerts = append(recentAlerts[:0:0], recentAlerts[len(recentAlerts)-alertFooter:]...)
+	}
+}
+
+// refreshAlerts rebuilds the footer from the buffer, for a config whose alert
+// rules may have changed.
+func refreshAlerts(cfg Config) {
+	if alertFooter <= 0 {
+		return
+	}
+	logsMutex.Lock()
+	defer logsMutex.Unlock()
+
+	var alerts []LogEntry
+	for i := len(storedLogs) - 1; i >= 0 && len(alerts) < alertFooter; i-- {
+		if isAlert(cfg, storedLogs[i].Text) {
+			alerts = append([]LogEntry{storedLogs[i]}, alerts...)
+		}
+	}
+	recentAlerts = alerts
+}
+
+// alertRows renders the footer: a header and the latest alerts. It is empty
+// until an alert arrives. The caller must hold logsMutex.
+func alertRows() []styledLine {
+	if len(recentAlerts) == 0 {
+		return nil
+	}
+	configMutex.RLock()
+	cfg := currentConfig
+	configMutex.RUnlock()
+
+	header := styledText(fmt.Sprintf("── recent alerts (%d) ──", len(recentAlerts)), Dim)
+	rows := []styledLine{header}
+	for _, entry := range recentAlerts {
+		rows = append(rows, decorate(entry, highlightLine(entry.Text, cfg)))
+	}
+	return rows
+}
+
+// writeAlertFooter writes the footer after a full reprint. The caller must
+// hold logsMutex.
+func writeAlertFooter(w io.Writer) {
+	for _, row := range alertRows() {
+		activeFormatter.Line(w, row)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCooldownKeepsFooter checks that a cooling-down alert rule still puts its
+// matches in the footer, and only holds back firing again.
+func TestCooldownKeepsFooter(t *testing.T) {
+	savedFooter, savedRecent, savedFired := alertFooter, recentAlerts, alertFired
+	alertFooter, recentAlerts, alertFired = 5, nil, make(map[string]time.Time)
+	t.Cleanup(func() { alertFooter, recentAlerts, alertFired = savedFooter, savedRecent, savedFired })
+
+	cfg := mustParseConfig(t, "fatal = red, alert, cooldown=1m\n")
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{ID: 1, Text: "fatal: disk full", Arrived: start},
+		{ID: 2, Text: "fatal: disk still full", Arrived: start.Add(time.Second)},
+		{ID: 3, Text: "all good", Arrived: start.Add(2 * time.Second)},
+		{ID: 4, Text: "fatal again", Arrived: start.Add(2 * time.Minute)},
+	}
+	wantFired := []bool{true, false, false, true}
+	logsMutex.Lock()
+	defer logsMutex.Unlock()
+	for i, entry := range entries {
+		fired, _ := firedAlerts(cfg, entry)
+		if got := len(fired) > 0; got != wantFired[i] {
+			t.Errorf("entry %d fired = %v, want %v", entry.ID, got, wantFired[i])
+		}
+	}
+
+	alertFired = make(map[string]time.Time)
+	for _, entry := range entries {
+		reactToAlerts(cfg, entry)
+	}
+	var ids []uint64
+	for _, entry := range recentAlerts {
+		ids = append(ids, entry.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 4 {
+		t.Errorf("footer has entries %v, want [1 2 4]", ids)
+	}
+}
+
+func TestCooldownNeedsAlert(t *testing.T) {
+	_, err := parseConfig("fatal = red, cooldown=1m\n")
+	if err == nil || !strings.Contains(err.Error(), "cooldown only applies to alert rules") {
+		t.Errorf("parseConfig error = %v, want one about cooldown without alert", err)
+	}
+	mustParseConfig(t, "fatal = red, cooldown=1m, alert\n")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// attributeCodes are the SGR attributes a color spec may add with "+", as in
+// "red+bold" or "red+blink".
+var attributeCodes = map[string]string{
+	"bold":      "\033[1m",
+	"dim":       "\033[2m",
+	"italic":    "\033[3m",
+	"underline": "\033[4m",
+	"blink":     "\033[5m",
+	"reverse":   "\033[7m",
+}
+
+// attributeOrder is the order attributes are written back out in.
+var attributeOrder = []string{"bold", "dim", "italic", "underline", "blink", "reverse"}
+
+// noBlink drops the blink attribute from every rule (--no-blink).
+var noBlink bool
+
+// blinkAllowed reports whether rules may blink: only on a terminal, and only
+// unless --no-blink is set.
+func blinkAllowed() bool {
+	return !noBlink && isTerminal(os.Stdout)
+}
+
+// withoutBlink removes the blink attribute from a style. Rules keep blink, so
+// that configs written back out keep it too; it is only dropped when a line
+// is rendered and blinkAllowed says no.
+func withoutBlink(style string) string {
+	return strings.ReplaceAll(style, attributeCodes["blink"], "")
+}
+
+// parseColorSpec returns the escape sequence for a color name followed by any
+// "+attribute" parts. Unknown names are ignored, like unknown colors.
+func parseColorSpec(spec string) string {
+	parts := strings.Split(spec, "+")
+	code := getColor(strings.TrimSpace(parts[0]))
+	for _, part := range parts[1:] {
+		code += attributeCodes[strings.ToLower(strings.TrimSpace(part))]
+	}
+	return code
+}
+
+// isColorSpec reports whether spec is a color name with optional attributes.
+func isColorSpec(spec string) bool {
+	parts := strings.Split(spec, "+")
+	if !isColorName(strings.TrimSpace(parts[0])) {
+		return false
+	}
+	for _, part := range parts[1:] {
+		if _, ok := attributeCodes[strings.ToLower(strings.TrimSpace(part))]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// colorSpecName returns the color spec for an escape sequence made by
+// parseColorSpec, the inverse of it.
+func colorSpecName(code string) string {
+	var attributes []string
+	for _, name := range attributeOrder {
+		if attribute := attributeCodes[name]; strings.Contains(code, attribute) {
+			code = strings.Replace(code, attribute, "", 1)
+			attributes = append(attributes, name)
+		}
+	}
+	return strings.Join(append([]string{colorName(code)}, attributes...), "+")
+}
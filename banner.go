@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// bannerInfo describes the invocation for the startup banner.
+type bannerInfo struct {
+	sources []string
+	modes   []string
+}
+
+// writeBanner prints a short summary of the effective settings. It stays on
+// screen until the first reprint clears it.
+func writeBanner(w io.Writer, info bannerInfo) {
+	configMutex.RLock()
+	cfg := currentConfig
+	configMutex.RUnlock()
+
+	filter := cfg.Filter
+	if filter == "" {
+		filter = "(none)"
+	}
+	modes := "(none)"
+	if len(info.modes) > 0 {
+		modes = strings.Join(info.modes, ", ")
+	}
+	fmt.Fprintf(w, "%sloggo%s waiting for input\n", Bold, Reset)
+	fmt.Fprintf(w, "  input   %s\n", strings.Join(info.sources, ", "))
+	fmt.Fprintf(w, "  config  %s\n", currentConfigFile())
+	fmt.Fprintf(w, "  filter  %s\n", filter)
+	fmt.Fprintf(w, "  rules   %d\n", len(cfg.Highlights))
+	fmt.Fprintf(w, "  modes   %s\n", modes)
+	out.endFrame()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Options for --baseline.
+var baselinePath string
+var onlyNew bool
+
+// baselineLines holds the normalized lines of the baseline file, or nil when
+// no baseline was given.
+var baselineLines map[string]bool
+
+// baselineMarker is prepended to lines that aren't in the baseline.
+const baselineMarker = "+"
+
+// digitRun matches the numbers normalizeLine masks out.
+var digitRun = regexp.MustCompile(`[0-9]+`)
+
+// normalizeLine reduces a line to what should match between two runs of the
+// same job: colors are removed, numbers such as timestamps, IDs, and durations
+// are masked, and whitespace is collapsed.
+func normalizeLine(line string) string {
+	line = digitRun.ReplaceAllString(stripANSI(line), "#")
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// loadBaseline reads the baseline file into baselineLines.
+func loadBaseline(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	baselineLines = make(map[string]bool)
+	scanner := bufio.NewScanner(decodeInput(f))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		baselineLines[normalizeLine(scanner.Text())] = true
+	}
+	return scanner.Err()
+}
+
+// inBaseline reports whether a line appears in the baseline. Every line does
+// when no baseline was given.
+func inBaseline(line string) bool {
+	return baselineLines == nil || baselineLines[normalizeLine(line)]
+}
@@ -0,0 +1,61 @@
+package main
+
+// matchBrackets colors matched (), [], {} and "" pairs by nesting depth, and
+// unbalanced ones in red (--match-brackets).
+var matchBrackets bool
+
+// bracketColors are the colors of matched pairs, by nesting depth.
+var bracketColors = []string{Cyan, Magenta, Yellow, Blue, Green}
+
+// closingBrackets maps each closing bracket to its opening one.
+var closingBrackets = map[byte]byte{')': '(', ']': '[', '}': '{'}
+
+// bracketSpans colors the delimiters of a line. Brackets inside double quotes
+// are not counted, and a backslash escapes a quote inside them.
+func bracketSpans(line string) []span {
+	var spans []span
+	var stack []int // Positions of unclosed opening brackets
+	quote := -1     // Position of the open double quote, if inside one
+	pair := func(open, close int) {
+		color := bracketColors[len(stack)%len(bracketColors)]
+		spans = append(spans, span{open, open + 1, color}, span{close, close + 1, color})
+	}
+	unbalanced := func(i int) {
+		spans = append(spans, span{i, i + 1, BrightRed})
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if quote >= 0 {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				pair(quote, i)
+				quote = -1
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			quote = i
+		case '(', '[', '{':
+			stack = append(stack, i)
+		case ')', ']', '}':
+			if n := len(stack); n > 0 && line[stack[n-1]] == closingBrackets[c] {
+				open := stack[n-1]
+				stack = stack[:n-1]
+				pair(open, i)
+			} else {
+				unbalanced(i)
+			}
+		}
+	}
+	for _, open := range stack {
+		unbalanced(open)
+	}
+	if quote >= 0 {
+		unbalanced(quote)
+	}
+	return spans
+}
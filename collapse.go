@@ -0,0 +1,58 @@
+package main
+
+import "fmt"
+
+// maxDisplay limits a render to the first and last N matching lines (--max-display).
+var maxDisplay int
+
+// displayExpanded shows every match despite --max-display, toggled with x.
+// It is guarded by viewMutex.
+var displayExpanded bool
+
+// collapseMatches keeps the first and last maxDisplay log lines and replaces
+// the ones in between with a single summary line.
+func collapseMatches(lines []displayLine) []displayLine {
+	viewMutex.Lock()
+	expanded := displayExpanded
+	viewMutex.Unlock()
+
+	total := 0
+	for _, line := range lines {
+		if line.isLog {
+			total++
+		}
+	}
+	if maxDisplay <= 0 || expanded || total <= 2*maxDisplay {
+		return lines
+	}
+
+	shown := func(k int) bool { return k < maxDisplay || k >= total-maxDisplay }
+	var kept []displayLine
+	k := 0 // Index of the next log line
+	for _, line := range lines {
+		if !line.isLog {
+			// Keep separators only between two shown lines.
+			if k > 0 && shown(k-1) && shown(k) {
+				kept = append(kept, line)
+			}
+			continue
+		}
+		if k == maxDisplay {
+			summary := fmt.Sprintf("… %d more matches …", total-2*maxDisplay)
+			kept = append(kept, displayLine{styledLine: styledText(summary, Dim)})
+		}
+		if shown(k) {
+			kept = append(kept, line)
+		}
+		k++
+	}
+	return kept
+}
+
+// toggleExpanded switches between the collapsed and full view of the matches.
+func toggleExpanded() {
+	viewMutex.Lock()
+	displayExpanded = !displayExpanded
+	viewMutex.Unlock()
+	reprintLogs()
+}
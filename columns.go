@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Options for showing selected columns of delimited lines (--cols).
+var columns []int       // 0-based indexes to show, in display order
+var delimiter string    // Column separator; "" splits on runs of whitespace
+var matchProjected bool // Filter and highlight the projected line instead of the full one
+
+var whitespaceFields = regexp.MustCompile(`\S+`)
+
+// parseColumns parses a comma-separated list of 1-based column numbers.
+func parseColumns(value string) ([]int, error) {
+	var cols []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q is not a column number (they start at 1)", part)
+		}
+		cols = append(cols, n-1)
+	}
+	return cols, nil
+}
+
+// columnRanges returns the byte range of each column of a line.
+func columnRanges(line string) [][2]int {
+	if delimiter == "" {
+		var ranges [][2]int
+		for _, loc := range whitespaceFields.FindAllStringIndex(line, -1) {
+			ranges = append(ranges, [2]int{loc[0], loc[1]})
+		}
+		return ranges
+	}
+	var ranges [][2]int
+	start := 0
+	for {
+		i := strings.Index(line[start:], delimiter)
+		if i < 0 {
+			return append(ranges, [2]int{start, len(line)})
+		}
+		ranges = append(ranges, [2]int{start, start + i})
+		start += i + len(delimiter)
+	}
+}
+
+// outputDelimiter joins the projected columns.
+func outputDelimiter() string {
+	if delimiter == "" {
+		return " "
+	}
+	return delimiter
+}
+
+// projectLine returns the selected columns of a line in the selected order.
+// Columns past the end of the line are empty.
+func projectLine(line string) string {
+	if len(columns) == 0 {
+		return line
+	}
+	return projectSpans(line, nil).text
+}
+
+// projectSpans is projectLine for a highlighted line: each column keeps the
+// colored spans that fall inside it.
+func projectSpans(line string, spans []span) styledLine {
+	ranges := columnRanges(line)
+	parts := make([]styledLine, len(columns))
+	for i, col := range columns {
+		if col >= len(ranges) {
+			continue
+		}
+		start, end := ranges[col][0], ranges[col][1]
+		var inside []span
+		for _, s := range spans {
+			if s.start < end && s.end > start {
+				inside = append(inside, span{max(s.start, start) - start, min(s.end, end) - start, s.color})
+			}
+		}
+		parts[i] = styledLine{text: line[start:end], spans: inside}
+	}
+	return joinStyled(parts, outputDelimiter())
+}
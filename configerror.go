@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// configError is the error from the last config reload, or nil once a reload
+// succeeds. Guarded by configErrorMutex.
+var configErrorMutex sync.Mutex
+var configError error
+
+// reportConfigError records the outcome of a config reload, printing new
+// errors to stderr outside interactive mode. It reports whether the error
+// shown to the user changed.
+func reportConfigError(err error) bool {
+	configErrorMutex.Lock()
+	defer configErrorMutex.Unlock()
+
+	previous := ""
+	if configError != nil {
+		previous = configError.Error()
+	}
+	configError = err
+	if err == nil {
+		return previous != ""
+	}
+	if err.Error() == previous {
+		return false
+	}
+	if !interactive {
+		fmt.Fprintln(os.Stderr, "Error reloading config file:", err)
+	}
+	return true
+}
+
+// configErrorMessage describes a failed reload for the status bar, or returns
+// "" if the last reload succeeded.
+func configErrorMessage() string {
+	configErrorMutex.Lock()
+	defer configErrorMutex.Unlock()
+	if configError == nil {
+		return ""
+	}
+	return fmt.Sprintf("config reload failed: %v, keeping previous", configError)
+}
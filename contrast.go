@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options for adjusting colors to the terminal background. --auto-contrast
+// only changes the colors loggo hands out itself; --auto-contrast-rules also
+// changes colors chosen in the config.
+var autoContrast bool
+var autoContrastRules bool
+
+// darkBackground is the detected or given --background.
+var darkBackground = true
+
+// darkYellow is a yellow that stays readable on a light background.
+const darkYellow = "\033[38;5;136m"
+
+// unreadable lists, per background, colors that are hard to read on it and a
+// readable replacement of the same hue.
+var unreadable = map[bool]map[string]string{
+	true: {Blue: BrightBlue},
+	false: {
+		Yellow: darkYellow, BrightYellow: darkYellow, BrightGreen: Green, BrightCyan: Cyan,
+	},
+}
+
+// backgroundQueryTimeout is how long to wait for the terminal to answer the
+// background color query.
+const backgroundQueryTimeout = 200 * time.Millisecond
+
+// parseBackground resolves --background: dark, light, or auto. Auto asks the
+// terminal when --auto-contrast needs the answer, then falls back to
+// COLORFGBG, then assumes dark.
+func parseBackground(value string) (bool, error) {
+	switch value {
+	case "dark":
+		return true, nil
+	case "light":
+		return false, nil
+	case "auto":
+		if autoContrast {
+			if dark, ok := queryBackground(); ok {
+				return dark, nil
+			}
+		}
+		dark, _ := colorFgBgDark(os.Getenv("COLORFGBG"))
+		return dark, nil
+	}
+	return true, fmt.Errorf("must be dark, light, or auto, got %q", value)
+}
+
+// queryBackground asks the terminal for its background color with OSC 11 and
+// reports whether it is dark. It gives up if there is no terminal or it
+// doesn't answer within backgroundQueryTimeout.
+func queryBackground() (bool, bool) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return true, false
+	}
+	defer f.Close()
+	saved, err := stty("-g")
+	if err != nil {
+		return true, false
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return true, false
+	}
+	defer stty(saved)
+
+	if _, err := f.WriteString("\033]11;?\a"); err != nil {
+		return true, false
+	}
+	f.SetReadDeadline(time.Now().Add(backgroundQueryTimeout))
+	var reply []byte
+	buf := make([]byte, 64)
+	for !bytes.HasSuffix(reply, []byte("\a")) && !bytes.HasSuffix(reply, []byte("\033\\")) {
+		n, err := f.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if err != nil {
+			break
+		}
+	}
+	return parseBackgroundReply(string(reply))
+}
+
+// parseBackgroundReply reads an OSC 11 reply such as
+// "\033]11;rgb:1e1e/1e1e/1e1e\a" and reports whether the color is dark.
+func parseBackgroundReply(reply string) (bool, bool) {
+	_, rgb, ok := strings.Cut(reply, "rgb:")
+	if !ok {
+		return true, false
+	}
+	rgb = strings.TrimRight(rgb, "\a\033\\")
+	parts := strings.Split(rgb, "/")
+	if len(parts) != 3 {
+		return true, false
+	}
+	// Perceived brightness, with each channel scaled to 0-1.
+	weights := []float64{0.299, 0.587, 0.114}
+	brightness := 0.0
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 16, 16)
+		if err != nil || len(part) == 0 || len(part) > 4 {
+			return true, false
+		}
+		brightness += weights[i] * float64(v) / float64(uint(1)<<(4*len(part))-1)
+	}
+	return brightness < 0.5, true
+}
+
+// colorFgBgDark reads the background from a COLORFGBG value such as "15;0",
+// whose last field is the background's ANSI color number.
+func colorFgBgDark(value string) (bool, bool) {
+	fields := strings.Split(value, ";")
+	bg, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || bg < 0 || bg > 15 {
+		return true, false
+	}
+	return bg != 7 && bg < 9, true
+}
+
+// contrastColor returns a readable replacement for a color code, or the code
+// itself. Attribute codes after the color are kept.
+func contrastColor(code string) string {
+	for from, to := range unreadable[darkBackground] {
+		if strings.HasPrefix(code, from) {
+			return to + code[len(from):]
+		}
+	}
+	return code
+}
+
+// ruleColor is the color a config rule is drawn in: as written, unless
+// --auto-contrast-rules asks for it to be made readable.
+func ruleColor(code string) string {
+	if autoContrastRules {
+		return contrastColor(code)
+	}
+	return code
+}
+
+// contrastPalette drops the palette colors that are unreadable on the
+// background, keeping the order so neighboring keys still differ in hue.
+func contrastPalette() []string {
+	var readable []string
+	for _, color := range palette {
+		if _, bad := unreadable[darkBackground][color]; !bad {
+			readable = append(readable, color)
+		}
+	}
+	return readable
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// displaySuspended stops reprints while another program owns the terminal.
+// Guarded by renderMutex.
+var displaySuspended bool
+
+// editConfig opens the config file in $VISUAL or $EDITOR, falling back to vi,
+// then reloads it. The display is suspended and the terminal restored to its
+// normal mode while the editor runs.
+func editConfig() {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	resumeKeys, err := pauseKeys()
+	if err != nil {
+		showMessage("Error pausing keyboard input: %v", err)
+		return
+	}
+	renderMutex.Lock()
+	displaySuspended = true
+	renderMutex.Unlock()
+	stty(savedTerminalState)
+	fmt.Fprint(tty, ShowCursor+ClearScreen)
+
+	// Run through the shell so $EDITOR may carry arguments, as in "code -w".
+	// The shell opens the terminal itself, since the viewer's handle is in
+	// non-blocking mode.
+	cmd := exec.Command("sh", "-c", editor+` "$1" </dev/tty >/dev/tty 2>&1`, "sh", currentConfigFile())
+	runErr := cmd.Run()
+
+	stty(cbreakMode...)
+	fmt.Fprint(tty, HideCursor)
+	renderMutex.Lock()
+	displaySuspended = false
+	renderMutex.Unlock()
+	resumeKeys()
+
+	if runErr != nil {
+		showMessage("Error running %s: %v", editor, runErr)
+		return
+	}
+	// A config error is on the status bar already; the message would hide it.
+	if !reloadConfig(currentConfigFile()) && configErrorMessage() == "" {
+		showMessage("Config unchanged")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// inputEncoding decodes input to UTF-8 before matching and display
+// (--encoding), or is nil to pass UTF-8 input through as it is.
+var inputEncoding encoding.Encoding
+
+// encodingAliases are names the WHATWG index lacks or maps differently: it
+// treats latin1 as windows-1252.
+var encodingAliases = map[string]encoding.Encoding{
+	"latin1":     charmap.ISO8859_1,
+	"iso-8859-1": charmap.ISO8859_1,
+	"utf16":      unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16":     unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf16le":    unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf16be":    unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+}
+
+// parseEncoding resolves an --encoding name, such as latin1, utf16,
+// windows-1252, or shift_jis.
+func parseEncoding(name string) (encoding.Encoding, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "utf8" || name == "utf-8" {
+		return nil, nil
+	}
+	if enc, ok := encodingAliases[name]; ok {
+		return enc, nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	return enc, nil
+}
+
+// decodeInput wraps an input so it reads as UTF-8.
+func decodeInput(r io.Reader) io.Reader {
+	if inputEncoding == nil {
+		return r
+	}
+	return transform.NewReader(r, inputEncoding.NewDecoder())
+}
+
+// decodeBytes decodes one chunk of input, such as a Kafka message.
+func decodeBytes(b []byte) string {
+	if inputEncoding == nil {
+		return string(b)
+	}
+	decoded, err := inputEncoding.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(decoded)
+}
+
+// encodeRaw converts a decoded line back to the input encoding, so that
+// --record keeps the bytes as they arrived. Runes the encoding can't
+// represent are replaced.
+func encodeRaw(line string) string {
+	if inputEncoding == nil {
+		return line
+	}
+	encoded, err := encoding.ReplaceUnsupported(inputEncoding.NewEncoder()).String(line)
+	if err != nil {
+		return line
+	}
+	return encoded
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// enrichPattern captures the tokens looked up by --enrich-cmd: its first
+// capture group if it has one, otherwise the whole match.
+var enrichPattern = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+
+// enrichCmd is a long-running subprocess that annotates tokens.
+//
+// Each token is written to the subprocess's stdin, and the subprocess answers
+// with one line on stdout: the annotation text, or an empty line for none.
+// Answers are cached per token. If the subprocess fails, lines are shown
+// without annotations until it can be restarted.
+type enrichCmd struct {
+	mu      sync.Mutex // Held for a whole lookup, so tokens are answered in order
+	process *lineProcess
+
+	cache map[string]string
+}
+
+// enricher is the command configured with --enrich-cmd, if any.
+var enricher *enrichCmd
+
+// newEnrichCmd creates an enricher for the given command line. The process is
+// started lazily on the first token.
+func newEnrichCmd(command string) *enrichCmd {
+	return &enrichCmd{process: newLineProcess("enrich command", command), cache: make(map[string]string)}
+}
+
+// lookup returns the annotation for a token, or "" if there is none or the
+// lookup failed.
+func (e *enrichCmd) lookup(token string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if annotation, ok := e.cache[token]; ok {
+		return annotation
+	}
+	annotation, ok := e.process.ask(token)
+	if !ok {
+		return ""
+	}
+	e.cache[token] = annotation
+	return annotation
+}
+
+// annotations returns the annotations for the distinct tokens in a line,
+// formatted for display after it, or an empty line if there are none.
+func (e *enrichCmd) annotations(line string) styledLine {
+	seen := make(map[string]bool)
+	var notes []string
+	for _, match := range enrichPattern.FindAllStringSubmatch(line, -1) {
+		token := captureKey(match)
+		if token == "" || seen[token] {
+			continue
+		}
+		seen[token] = true
+		if annotation := e.lookup(token); annotation != "" {
+			notes = append(notes, token+": "+annotation)
+		}
+	}
+	if len(notes) == 0 {
+		return styledLine{}
+	}
+	return styledText("["+strings.Join(notes, "; ")+"]", Dim)
+}
+
+// stop shuts the subprocess down. It doesn't wait for a lookup in progress.
+func (e *enrichCmd) stop() {
+	e.process.stop()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// eventFile receives a JSON event per alert rule match (--event-fd), for
+// scripts that react to matches while the view is watched.
+var eventFile *os.File
+
+// matchEvent is one line of the --event-fd stream.
+type matchEvent struct {
+	Keyword string `json:"keyword"`
+	Line    string `json:"line"`
+	TS      string `json:"ts"`
+	Source  string `json:"source"`
+}
+
+// openEventFD opens an inherited file descriptor for events.
+func openEventFD(fd int) error {
+	if fd < 3 {
+		return fmt.Errorf("must be 3 or higher, got %d", fd)
+	}
+	f := os.NewFile(uintptr(fd), "event-fd")
+	if _, err := f.Stat(); err != nil {
+		return err
+	}
+	eventFile = f
+	return nil
+}
+
+// emitEvents writes an event for each alert rule that fired on an entry.
+// Writing stops after the first error, such as the reader going away. The
+// caller must hold logsMutex for writing, which keeps events in arrival order.
+func emitEvents(fired []HighlightRule, entry LogEntry) {
+	if eventFile == nil {
+		return
+	}
+	for _, rule := range fired {
+		data, _ := json.Marshal(matchEvent{
+			Keyword: rule.Word,
+			Line:    entry.Text,
+			TS:      entry.Arrived.Format(time.RFC3339Nano),
+			Source:  entry.Source,
+		})
+		if _, err := eventFile.Write(append(data, '\n')); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing to --event-fd, no longer sending events:", err)
+			eventFile = nil
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// explain prints why each line is shown or hidden to stderr (--explain).
+var explain bool
+
+// explainPreview is how much of a line --explain quotes.
+const explainPreview = 60
+
+// explainEntry reports the filtering decision for a newly stored entry. The
+// caller must hold logsMutex, since mute state is decided under it.
+func explainEntry(cfg Config, entry LogEntry) {
+	fmt.Fprintln(os.Stderr, explanation(cfg, entry))
+}
+
+// explanation returns the --explain line for an entry.
+func explanation(cfg Config, entry LogEntry) string {
+	text := entry.Text
+	if matchTrimmed {
+		text = trimLine(text)
+	}
+	if matchProjected {
+		text = projectLine(text)
+	}
+	keep, text, reason := checkFilters(cfg, text)
+	reasons := []string{reason}
+	verdict := "shown"
+	switch {
+	case !keep:
+		// --focus dims the lines the filters miss rather than hiding them.
+		verdict = "hidden"
+		if focusMode {
+			verdict = "dimmed"
+		}
+	case entry.Muted != "":
+		verdict = "hidden"
+		reasons = append(reasons, fmt.Sprintf("suppressed by mute_after on %q", entry.Muted))
+	case onlyNew && !entry.New:
+		verdict = "hidden"
+		reasons = append(reasons, "in the --baseline file")
+	}
+	if words := matchedRules(cfg, text); len(words) > 0 {
+		reasons = append(reasons, "highlights "+strings.Join(words, ", "))
+	}
+
+	preview := []rune(stripANSI(entry.Text))
+	if len(preview) > explainPreview {
+		preview = append(preview[:explainPreview-1], '…')
+	}
+	return fmt.Sprintf("%s %q: %s", verdict, string(preview), strings.Join(reasons, "; "))
+}
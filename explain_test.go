@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestExplainVerdict checks the verdict --explain gives: --focus dims the lines
+// the filters miss, but lines dropped by mute_after or --only-new stay hidden.
+func TestExplainVerdict(t *testing.T) {
+	savedFocus, savedOnlyNew := focusMode, onlyNew
+	t.Cleanup(func() { focusMode, onlyNew = savedFocus, savedOnlyNew })
+	cfg := mustParseConfig(t, "filter = disk\n")
+
+	tests := []struct {
+		name    string
+		entry   LogEntry
+		onlyNew bool
+		focus   bool
+		want    string
+	}{
+		{"passes the filter", LogEntry{Text: "disk full", New: true}, false, false, "shown"},
+		{"filtered out", LogEntry{Text: "all good", New: true}, false, false, "hidden"},
+		{"filtered out with --focus", LogEntry{Text: "all good", New: true}, false, true, "dimmed"},
+		{"muted", LogEntry{Text: "disk full", Muted: "disk", New: true}, false, false, "hidden"},
+		{"muted with --focus", LogEntry{Text: "disk full", Muted: "disk", New: true}, false, true, "hidden"},
+		{"in the baseline", LogEntry{Text: "disk full"}, true, false, "hidden"},
+		{"in the baseline with --focus", LogEntry{Text: "disk full"}, true, true, "hidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			focusMode, onlyNew = tt.focus, tt.onlyNew
+			got := explanation(cfg, tt.entry)
+			if verdict, _, _ := strings.Cut(got, " "); verdict != tt.want {
+				t.Errorf("explanation = %q, want verdict %q", got, tt.want)
+			}
+		})
+	}
+}
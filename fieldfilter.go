@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A field filter (--field-filter) tests one nested value of a JSON line by a
+// JSONPath-like selector, for example:
+//
+//	$.request.headers.host=example.com
+//	$.items[0].price>=100
+//	$.error
+//
+// Keys follow ".", or are quoted in brackets as ["a.b"], and array indexes are
+// in brackets. A selector alone tests that the value exists. Comparisons use
+// the --query operators and number handling. Lines that aren't JSON objects
+// are left to the other filters.
+
+// fieldFilters are the parsed --field-filter flags, all of which must pass.
+var fieldFilters []fieldFilter
+
+// fieldFilter is one parsed --field-filter.
+type fieldFilter struct {
+	path    []any // Keys (string) and array indexes (int)
+	compare *compareNode
+}
+
+// parseFieldFilter parses a selector with an optional comparison.
+func parseFieldFilter(src string) (fieldFilter, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(src), "$")
+	if !ok {
+		return fieldFilter{}, fmt.Errorf("selector must start with $")
+	}
+	var filter fieldFilter
+	for rest != "" && !strings.ContainsAny(rest[:1], "=!<>~ ") {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[=!<>~ ")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return fieldFilter{}, fmt.Errorf("empty key in %q", src)
+			}
+			filter.path = append(filter.path, rest[1:end+1])
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return fieldFilter{}, fmt.Errorf("missing ] in %q", src)
+			}
+			inside := rest[1:end]
+			if key, err := strconv.Unquote(inside); err == nil {
+				filter.path = append(filter.path, key)
+			} else if index, err := strconv.Atoi(inside); err == nil && index >= 0 {
+				filter.path = append(filter.path, index)
+			} else {
+				return fieldFilter{}, fmt.Errorf("expected an index or quoted key in [%s]", inside)
+			}
+			rest = rest[end+1:]
+		default:
+			return fieldFilter{}, fmt.Errorf("unexpected %q in selector", rest[:1])
+		}
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return filter, nil
+	}
+	for _, op := range queryOperators {
+		if value, ok := strings.CutPrefix(rest, op); ok {
+			value = strings.TrimSpace(value)
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			compare := &compareNode{field: "value", op: op, value: value}
+			if op == "~" || op == "!~" {
+				re, err := regexp.Compile(value)
+				if err != nil {
+					return fieldFilter{}, fmt.Errorf("invalid regex: %v", err)
+				}
+				compare.re = re
+			}
+			filter.compare = compare
+			return filter, nil
+		}
+	}
+	return fieldFilter{}, fmt.Errorf("expected an operator after the selector, got %q", rest)
+}
+
+// lookup follows the selector into a decoded JSON value.
+func (f fieldFilter) lookup(value any) (any, bool) {
+	for _, step := range f.path {
+		switch step := step.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if value, ok = object[step]; !ok {
+				return nil, false
+			}
+		case int:
+			array, ok := value.([]any)
+			if !ok || step >= len(array) {
+				return nil, false
+			}
+			value = array[step]
+		}
+	}
+	return value, true
+}
+
+// fieldFiltersReject reports whether a JSON line fails any --field-filter.
+func fieldFiltersReject(line string) bool {
+	if len(fieldFilters) == 0 {
+		return false
+	}
+	object, ok := parseJSONObject(line)
+	if !ok {
+		return false
+	}
+	for _, filter := range fieldFilters {
+		value, found := filter.lookup(object)
+		if !found {
+			return true
+		}
+		if filter.compare != nil && !filter.compare.eval(map[string]string{"value": jsonString(value)}) {
+			return true
+		}
+	}
+	return false
+}
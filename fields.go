@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// logfmtPair matches key=value pairs, where the value may be double-quoted.
+var logfmtPair = regexp.MustCompile(`([\w.\-]+)=("(?:[^"\\]|\\.)*"|\S*)`)
+
+// parseJSONObject decodes a line that is a JSON object, keeping numbers exact.
+func parseJSONObject(line string) (map[string]any, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, false
+	}
+	return object, true
+}
+
+// parseFields extracts the top-level fields of a structured line, either a
+// JSON object or logfmt key=value pairs. It returns nil for unstructured lines.
+// Nested JSON values are returned as compact JSON text.
+func parseFields(line string) map[string]string {
+	if object, ok := parseJSONObject(line); ok {
+		fields := make(map[string]string, len(object))
+		for key, value := range object {
+			fields[key] = jsonString(value)
+		}
+		return fields
+	}
+
+	pairs := logfmtPair.FindAllStringSubmatch(line, -1)
+	if len(pairs) == 0 {
+		return nil
+	}
+	fields := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		value := pair[2]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		fields[pair[1]] = value
+	}
+	return fields
+}
+
+// jsonString renders a decoded JSON value as display text.
+func jsonString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(v)
+		return strings.TrimSpace(buf.String())
+	}
+}
+
+// fieldValue returns the value of one field of a structured line.
+func fieldValue(line, name string) (string, bool) {
+	value, ok := parseFields(line)[name]
+	return value, ok
+}
+
+// lineKey extracts the key a line is grouped or ordered by, as for --top,
+// --sort, and --color-by: the first capture group (else the whole match) of
+// pattern, or when pattern is nil, the value of the structured field.
+func lineKey(line string, pattern *regexp.Regexp, field string) (string, bool) {
+	if pattern == nil {
+		return fieldValue(line, field)
+	}
+	match := pattern.FindStringSubmatch(line)
+	if match == nil {
+		return "", false
+	}
+	return captureKey(match), true
+}
+
+// captureKey returns the first capture group of a regex match, or the whole
+// match when the regex has no groups.
+func captureKey(match []string) string {
+	if len(match) > 1 {
+		return match[1]
+	}
+	return match[0]
+}
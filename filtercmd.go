@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// filterResult is the decision an external filter made about a single line.
+type filterResult struct {
+	keep bool
+	line string
+}
+
+// filterCmd is a long-running subprocess consulted for each log line.
+//
+// Every line is written to the subprocess's stdin, and the subprocess answers
+// with one line on stdout: "keep", "drop", or "keep <rewritten line>".
+// Decisions are cached per distinct line, since logs are re-filtered on every
+// reprint.
+type filterCmd struct {
+	mu       sync.Mutex // Held for a whole query, so lines are answered in order
+	process  *lineProcess
+	failOpen bool
+
+	cache map[string]filterResult
+}
+
+// externalFilter is the filter command configured with --filter-cmd, if any.
+var externalFilter *filterCmd
+
+// newFilterCmd creates a filter for the given command line. The process is
+// started lazily on the first line.
+func newFilterCmd(command string, failOpen bool) *filterCmd {
+	return &filterCmd{
+		process:  newLineProcess("filter command", command),
+		failOpen: failOpen,
+		cache:    make(map[string]filterResult),
+	}
+}
+
+// parseFilterReply parses the subprocess's answer about a line.
+func parseFilterReply(line, reply string) (filterResult, error) {
+	verb, rewritten, hasRewrite := strings.Cut(reply, " ")
+	switch verb {
+	case "keep":
+		if hasRewrite {
+			return filterResult{keep: true, line: rewritten}, nil
+		}
+		return filterResult{keep: true, line: line}, nil
+	case "drop":
+		return filterResult{keep: false}, nil
+	default:
+		return filterResult{}, fmt.Errorf("unexpected reply %q", reply)
+	}
+}
+
+// decide reports whether the line should be kept, and the line to display.
+// If the subprocess fails or doesn't answer in time, --filter-cmd-fail
+// decides.
+func (f *filterCmd) decide(line string) (bool, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if res, ok := f.cache[line]; ok {
+		return res.keep, res.line
+	}
+
+	reply, ok := f.process.ask(line)
+	if !ok {
+		return f.failOpen, line
+	}
+	res, err := parseFilterReply(line, reply)
+	if err != nil {
+		f.process.fail(err)
+		return f.failOpen, line
+	}
+
+	f.cache[line] = res
+	return res.keep, res.line
+}
+
+// stop shuts the subprocess down. It doesn't wait for a query in progress.
+func (f *filterCmd) stop() {
+	f.process.stop()
+}
@@ -0,0 +1,51 @@
+package main
+
+import "strings"
+
+// markNew badges the first line each highlight keyword appears on (--mark-new).
+var markNew bool
+
+// First-seen state, guarded by logsMutex.
+var seenKeywords = make(map[string]bool)
+var seenKeywordSet string // The rule words seenKeywords was built for
+
+// newBadge is appended to lines that introduce a keyword.
+const newBadge = "(new)"
+
+// keywordSet identifies a config's highlight words, to detect when they change.
+func keywordSet(cfg Config) string {
+	words := make([]string, len(cfg.Highlights))
+	for i, rule := range cfg.Highlights {
+		words[i] = rule.Word
+	}
+	return strings.Join(words, "\x00")
+}
+
+// markFirstSeen flags the entry if it contains a keyword not seen before this
+// session. The caller must hold logsMutex for writing.
+func markFirstSeen(cfg Config, entry *LogEntry) {
+	entry.FirstSeen = false
+	for _, word := range matchedRules(cfg, entry.Text) {
+		if !seenKeywords[word] {
+			seenKeywords[word] = true
+			entry.FirstSeen = true
+		}
+	}
+}
+
+// refreshFirstSeen restarts first-seen tracking if the config's keywords have
+// changed, re-marking the stored lines in order.
+func refreshFirstSeen(cfg Config) {
+	logsMutex.Lock()
+	defer logsMutex.Unlock()
+
+	set := keywordSet(cfg)
+	if set == seenKeywordSet {
+		return
+	}
+	seenKeywordSet = set
+	seenKeywords = make(map[string]bool)
+	for i := range storedLogs {
+		markFirstSeen(cfg, &storedLogs[i])
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+)
+
+// unicodeFold compares text with full Unicode case folding instead of
+// lowercasing (--unicode-fold). It is slower, but matches pairs such as ß and
+// SS that lowercasing and (?i) miss.
+var unicodeFold bool
+
+// foldString case-folds s for comparison.
+func foldString(s string) string {
+	if !unicodeFold {
+		return strings.ToLower(s)
+	}
+	return cases.Fold().String(s)
+}
+
+// containsFold reports whether s contains substr, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(foldString(s), foldString(substr))
+}
+
+// foldedIndexes finds every occurrence of the already folded word in line
+// with full case folding, returning byte ranges of line. Folding can change
+// the length of the text, so each rune is folded separately and matches are
+// mapped back to whole runes of the original. The search resumes after the
+// rune a match ends in, so a rune that folds to several characters, like ß to
+// "ss", yields one match even when the word matches each of them.
+func foldedIndexes(line, folded string) [][]int {
+	if folded == "" {
+		return nil
+	}
+	caser := cases.Fold()
+	var b strings.Builder
+	var starts, ends []int // Original rune bounds for each byte of the folded text
+	var foldEnds []int     // Where the folded rune ends, for each byte of the folded text
+	for i := 0; i < len(line); {
+		_, size := utf8.DecodeRuneInString(line[i:])
+		f := caser.String(line[i : i+size])
+		b.WriteString(f)
+		for range len(f) {
+			starts = append(starts, i)
+			ends = append(ends, i+size)
+			foldEnds = append(foldEnds, b.Len())
+		}
+		i += size
+	}
+
+	text := b.String()
+	var locs [][]int
+	for offset := 0; offset < len(text); {
+		j := strings.Index(text[offset:], folded)
+		if j < 0 {
+			break
+		}
+		start, end := offset+j, offset+j+len(folded)
+		locs = append(locs, []int{starts[start], ends[end-1]})
+		offset = foldEnds[end-1]
+	}
+	return locs
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFoldedIndexes checks full case folding on pairs that lowercasing
+// misses, and that matches map back to whole runes of the original line.
+func TestFoldedIndexes(t *testing.T) {
+	saved := unicodeFold
+	unicodeFold = true
+	t.Cleanup(func() { unicodeFold = saved })
+
+	tests := []struct {
+		name, line, word string
+		want             [][]int
+	}{
+		{"ss matches ß", "Straße", "ss", [][]int{{4, 6}}},
+		{"ß matches SS", "STRASSE", "ß", [][]int{{4, 6}}},
+		{"SS matches ß at the end", "groß", "SS", [][]int{{3, 5}}},
+		{"ß within a word", "die Straße hier", "straße", [][]int{{4, 11}}},
+		{"one letter of ß matches the rune once", "ß", "s", [][]int{{0, 2}}},
+		{"Kelvin sign matches k", "5K", "k", [][]int{{1, 4}}},
+		{"Kelvin sign starts a word", "Kelvin", "kelvin", [][]int{{0, 8}}},
+		{"k matches the Kelvin sign and K", "K K", "k", [][]int{{0, 3}, {4, 5}}},
+		{"i matches dotted İ", "İstanbul", "i", [][]int{{0, 2}}},
+		{"İ matches itself", "İzmir", "İzmir", [][]int{{0, 6}}},
+		{"İ doesn't match I", "Izmir", "İzmir", nil},
+		{"i doesn't match dotless ı", "ılık", "i", nil},
+		{"dotless ı matches itself", "ılık", "ı", [][]int{{0, 2}, {3, 5}}},
+		{"ASCII after multibyte runes", "ß=ERROR", "error", [][]int{{3, 8}}},
+		{"empty word", "anything", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := foldedIndexes(tt.line, foldString(tt.word))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("foldedIndexes(%q, %q) = %v, want %v", tt.line, tt.word, got, tt.want)
+			}
+			for _, loc := range got {
+				if loc[0] < 0 || loc[1] > len(tt.line) || loc[0] >= loc[1] {
+					t.Fatalf("range %v out of bounds of %q", loc, tt.line)
+				}
+			}
+		})
+	}
+}
+
+// TestContainsFold checks that --unicode-fold makes the filter match the
+// pairs plain lowercasing misses.
+func TestContainsFold(t *testing.T) {
+	saved := unicodeFold
+	t.Cleanup(func() { unicodeFold = saved })
+
+	tests := []struct {
+		line, substr string
+		simple, full bool
+	}{
+		{"STRASSE", "straße", false, true},
+		{"Straße", "STRASSE", false, true},
+		{"5K", "k", true, true},
+		// Full folding keeps İ's dot as a combining U+0307, so only the
+		// simple lowercasing matches a plain i after it.
+		{"İstanbul", "istanbul", true, false},
+		{"İstanbul", "i̇stanbul", false, true},
+		{"ılık", "ilik", false, false},
+		{"Error", "error", true, true},
+	}
+	for _, tt := range tests {
+		unicodeFold = false
+		if got := containsFold(tt.line, tt.substr); got != tt.simple {
+			t.Errorf("containsFold(%q, %q) = %v, want %v", tt.line, tt.substr, got, tt.simple)
+		}
+		unicodeFold = true
+		if got := containsFold(tt.line, tt.substr); got != tt.full {
+			t.Errorf("with --unicode-fold, containsFold(%q, %q) = %v, want %v", tt.line, tt.substr, got, tt.full)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// followInterval is how often followed files are checked for new data and globs for new files.
+const followInterval = 500 * time.Millisecond
+
+// followFile reads a file from the start and keeps reading as it grows, like
+// "tail -f". It returns when the file is removed or replaced, or once stop is
+// closed while it waits for more data.
+func followFile(path, source string, stop <-chan struct{}) {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening followed file:", err)
+		return
+	}
+	defer file.Close()
+	defer setPartial(source, "")
+
+	reader := bufio.NewReader(decodeInput(file))
+	var partial string
+	for {
+		chunk, err := reader.ReadString('\n')
+		partial += chunk
+		if err == nil {
+			dropPartial(source)
+			appendRawLog(source, partial)
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			fmt.Fprintln(os.Stderr, "Error reading followed file:", err)
+			return
+		}
+		setPartial(source, strings.TrimRight(partial, "\r"))
+
+		// A decoder stops at the end of its input, so start a new one for the
+		// bytes still to come.
+		if inputEncoding != nil {
+			reader.Reset(decodeInput(file))
+		}
+
+		// At the end of the file: wait for more, then check it is still the same file.
+		select {
+		case <-stop:
+			return
+		case <-time.After(followInterval):
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		current, err := file.Stat()
+		if err != nil || !os.SameFile(info, current) {
+			return
+		}
+		if offset, err := file.Seek(0, io.SeekCurrent); err == nil && info.Size() < offset {
+			// Truncated in place: start over from the beginning.
+			file.Seek(0, io.SeekStart)
+			reader.Reset(decodeInput(file))
+			partial = ""
+		}
+	}
+}
+
+// followAll follows every file matching the glob pattern, including files
+// created after loggo starts. Lines are tagged with their file's base name.
+func followAll(pattern string) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		fmt.Fprintln(os.Stderr, "Error in --follow-all pattern:", err)
+		return
+	}
+
+	var followingMutex sync.Mutex
+	following := make(map[string]bool)
+
+	for {
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			followingMutex.Lock()
+			if following[path] {
+				followingMutex.Unlock()
+				continue
+			}
+			following[path] = true
+			followingMutex.Unlock()
+
+			go func(path string) {
+				followFile(path, filepath.Base(path), nil)
+
+				// Forget the file so it is picked up again if it reappears.
+				followingMutex.Lock()
+				delete(following, path)
+				followingMutex.Unlock()
+			}(path)
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// followLatest follows the most recently modified file matching the glob
+// pattern, switching when a newer file appears, as after rotation. Files it
+// has already left are not switched back to.
+func followLatest(pattern string) {
+	followed := make(map[string]bool)
+	var stop chan struct{}
+	var done chan struct{}
+	for {
+		if newest := newestMatch(pattern); newest != "" && !followed[newest] {
+			followed[newest] = true
+			if stop != nil {
+				close(stop)
+				<-done
+			}
+			stop, done = make(chan struct{}), make(chan struct{})
+			go func(path string, stop, done chan struct{}) {
+				defer close(done)
+				followFile(path, filepath.Base(path), stop)
+			}(newest, stop, done)
+		}
+		time.Sleep(followInterval)
+	}
+}
+
+// newestMatch returns the most recently modified file matching the pattern,
+// or "" if there is none.
+func newestMatch(pattern string) string {
+	matches, _ := filepath.Glob(pattern)
+	var newest string
+	var newestTime time.Time
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if newest == "" || info.ModTime().After(newestTime) {
+			newest, newestTime = path, info.ModTime()
+		}
+	}
+	return newest
+}
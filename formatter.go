@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Formatter writes lines in an output format (--format). Each line comes as
+// its text, the styled spans of it, and for log lines the entry's source and
+// timestamp, so decorations are formatted the same way as the highlights.
+type Formatter interface {
+	// Begin and End wrap each frame of output, for formats that need a
+	// document around the lines.
+	Begin(w io.Writer)
+	End(w io.Writer)
+	// Line writes one line.
+	Line(w io.Writer, line styledLine)
+	// Live reports whether a frame is written on every update, rather than
+	// once when loggo exits.
+	Live() bool
+}
+
+// activeFormatter formats everything written to stdout.
+var activeFormatter Formatter = ansiFormatter{}
+
+// finalFrame is set while writing the one frame of a formatter that isn't
+// live. Guarded by renderMutex.
+var finalFrame bool
+
+// writeFinalFrame writes the view once, at exit, for formatters like html.
+func writeFinalFrame() {
+	renderMutex.Lock()
+	finalFrame = true
+	renderMutex.Unlock()
+	reprintLogs()
+}
+
+// formatters are the choices for --format.
+var formatters = map[string]Formatter{
+	"ansi":  ansiFormatter{},
+	"plain": plainFormatter{},
+	"html":  htmlFormatter{},
+}
+
+// ansiFormatter writes lines with ANSI escapes, for terminals.
+type ansiFormatter struct{}
+
+func (ansiFormatter) Begin(io.Writer) {}
+func (ansiFormatter) End(io.Writer)   {}
+func (ansiFormatter) Live() bool      { return true }
+
+func (ansiFormatter) Line(w io.Writer, line styledLine) {
+	fmt.Fprintln(w, line.ansi())
+}
+
+// plainFormatter writes lines without any escapes. Redrawing needs a terminal
+// to clear, so when stdout is piped only the final view is written, once.
+type plainFormatter struct{}
+
+func (plainFormatter) Begin(io.Writer) {}
+func (plainFormatter) End(io.Writer)   {}
+func (plainFormatter) Live() bool      { return isTerminal(os.Stdout) }
+
+func (plainFormatter) Line(w io.Writer, line styledLine) {
+	fmt.Fprintln(w, stripANSI(line.text))
+}
+
+// htmlFormatter writes a standalone HTML page with the colors as inline
+// styles. A page can't be redrawn, so only the final view is written.
+type htmlFormatter struct{}
+
+func (htmlFormatter) Live() bool { return false }
+
+func (htmlFormatter) Begin(w io.Writer) {
+	fmt.Fprint(w, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>loggo</title></head>\n"+
+		"<body style=\"background:#1e1e1e;color:#d4d4d4\">\n<pre>\n")
+}
+
+func (htmlFormatter) End(w io.Writer) {
+	fmt.Fprint(w, "</pre>\n</body>\n</html>\n")
+}
+
+// Line writes the spans as inline styles. A log line is wrapped in a span
+// naming its source and timestamp.
+func (htmlFormatter) Line(w io.Writer, line styledLine) {
+	var b strings.Builder
+	var attrs []string
+	if line.source != "" {
+		attrs = append(attrs, fmt.Sprintf("data-source=\"%s\"", html.EscapeString(line.source)))
+	}
+	if !line.time.IsZero() {
+		attrs = append(attrs, fmt.Sprintf("data-time=\"%s\"", line.time.Format(time.RFC3339Nano)))
+	}
+	if len(attrs) > 0 {
+		fmt.Fprintf(&b, "<span %s>", strings.Join(attrs, " "))
+	}
+	openCSS := "" // Style of the open <span>, if any
+	line.segments(func(text string, styles []string) {
+		var style sgrState
+		for _, s := range styles {
+			style.applyStyle(s)
+		}
+		if css := style.css(); css != openCSS {
+			if openCSS != "" {
+				b.WriteString("</span>")
+			}
+			if css != "" {
+				fmt.Fprintf(&b, "<span style=\"%s\">", css)
+			}
+			openCSS = css
+		}
+		// Escapes from the input have no style to map to.
+		b.WriteString(html.EscapeString(stripANSI(text)))
+	})
+	if openCSS != "" {
+		b.WriteString("</span>")
+	}
+	if len(attrs) > 0 {
+		b.WriteString("</span>")
+	}
+	fmt.Fprintln(w, b.String())
+}
+
+// sgrState is the text style set by ANSI SGR sequences.
+type sgrState struct {
+	color                                        string // CSS color, "" for the default
+	bold, dim, italic, underline, blink, reverse bool
+}
+
+// applyStyle updates the style from a span's color, one or more SGR sequences
+// like Bold+Yellow.
+func (s *sgrState) applyStyle(style string) {
+	for _, seq := range strings.SplitAfter(style, "m") {
+		if params, ok := strings.CutPrefix(seq, "\033["); ok {
+			s.apply(strings.TrimSuffix(params, "m"))
+		}
+	}
+}
+
+// apply updates the style from the parameters of one SGR sequence.
+func (s *sgrState) apply(params string) {
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		code, _ := strconv.Atoi(codes[i])
+		switch {
+		case code == 0:
+			*s = sgrState{}
+		case code == 1:
+			s.bold = true
+		case code == 2:
+			s.dim = true
+		case code == 3:
+			s.italic = true
+		case code == 4:
+			s.underline = true
+		case code == 5:
+			s.blink = true
+		case code == 7:
+			s.reverse = true
+		case code == 22:
+			s.bold, s.dim = false, false
+		case code == 23:
+			s.italic = false
+		case code == 24:
+			s.underline = false
+		case code == 25:
+			s.blink = false
+		case code == 27:
+			s.reverse = false
+		case code >= 30 && code <= 37:
+			s.color = ansiColors[code-30]
+		case code >= 90 && code <= 97:
+			s.color = ansiColors[code-90+8]
+		case code == 39:
+			s.color = ""
+		case code == 38 && i+2 < len(codes) && codes[i+1] == "5":
+			n, _ := strconv.Atoi(codes[i+2])
+			s.color = color256(n)
+			i += 2
+		}
+	}
+}
+
+// css returns the inline style for the state, or "" for plain text.
+func (s sgrState) css() string {
+	var parts []string
+	if s.color != "" {
+		if s.reverse {
+			parts = append(parts, "background:"+s.color, "color:#1e1e1e")
+		} else {
+			parts = append(parts, "color:"+s.color)
+		}
+	} else if s.reverse {
+		parts = append(parts, "background:#d4d4d4", "color:#1e1e1e")
+	}
+	if s.bold {
+		parts = append(parts, "font-weight:bold")
+	}
+	if s.dim {
+		parts = append(parts, "opacity:0.6")
+	}
+	if s.italic {
+		parts = append(parts, "font-style:italic")
+	}
+	if s.underline {
+		parts = append(parts, "text-decoration:underline")
+	}
+	return strings.Join(parts, ";")
+}
+
+// ansiColors are CSS colors for the 16 basic ANSI colors.
+var ansiColors = []string{
+	"#000000", "#cd3131", "#0dbc79", "#e5e510", "#2472c8", "#bc3fbc", "#11a8cd", "#e5e5e5",
+	"#666666", "#f14c4c", "#23d18b", "#f5f543", "#3b8eea", "#d670d6", "#29b8db", "#ffffff",
+}
+
+// color256 returns the CSS color of an entry in the 256-color palette.
+func color256(n int) string {
+	switch {
+	case n < 0 || n > 255:
+		return ""
+	case n < 16:
+		return ansiColors[n]
+	case n < 232:
+		n -= 16
+		level := func(v int) int {
+			if v == 0 {
+				return 0
+			}
+			return 55 + v*40
+		}
+		return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+	default:
+		v := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+	}
+}
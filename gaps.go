@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Options for --gap-separator.
+var gapThreshold time.Duration
+var gapFormat string
+
+// gapBetween returns the time between two entries, using parsed timestamps
+// when both lines have one and arrival times otherwise.
+func gapBetween(prev, next LogEntry) time.Duration {
+	if !prev.Time.IsZero() && !next.Time.IsZero() {
+		return next.Time.Sub(prev.Time)
+	}
+	return next.Arrived.Sub(prev.Arrived)
+}
+
+// gapSeparator returns a dimmed separator line for a pause, or an empty line
+// if the pause is shorter than the threshold.
+func gapSeparator(prev, next LogEntry) styledLine {
+	gap := gapBetween(prev, next)
+	if gapThreshold <= 0 || gap < gapThreshold {
+		return styledLine{}
+	}
+	return styledText(strings.ReplaceAll(gapFormat, "{gap}", gap.Round(time.Millisecond).String()), Dim)
+}
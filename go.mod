@@ -1,5 +1,15 @@
 module github.com/dixler/loggo
 
-go 1.23
+go 1.23.0
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/expr-lang/expr v1.16.9
+	github.com/segmentio/kafka-go v0.4.51
+	golang.org/x/text v0.23.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+)
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// helpVisible is set while the key help is drawn over the view. Guarded by
+// viewMutex.
+var helpVisible bool
+
+// showHelp draws the key help over the view until the next key, which only
+// closes it.
+func showHelp() {
+	viewMutex.Lock()
+	helpVisible = true
+	viewMutex.Unlock()
+	reprintLogs()
+
+	<-keyInput
+	viewMutex.Lock()
+	helpVisible = false
+	viewMutex.Unlock()
+	reprintLogs()
+}
+
+// helpBox renders the key help, generated from keyBindings, as the rows of a
+// framed box.
+func helpBox() []string {
+	keys := make([]string, len(keyBindings))
+	width := 0
+	for i, binding := range keyBindings {
+		keys[i] = strings.Join(binding.keys, "/")
+		width = max(width, len(keys[i]))
+	}
+	var lines []string
+	for i, binding := range keyBindings {
+		lines = append(lines, fmt.Sprintf("%-*s  %s", width, keys[i], binding.help))
+	}
+	lines = append(lines, "", "Press any key to close")
+
+	inner := 0
+	for _, line := range lines {
+		inner = max(inner, visibleWidth(line))
+	}
+	rows := []string{"┌─ Keys " + strings.Repeat("─", max(inner-5, 0)) + "┐"}
+	for _, line := range lines {
+		rows = append(rows, "│ "+line+strings.Repeat(" ", inner-visibleWidth(line))+" │")
+	}
+	return append(rows, "└"+strings.Repeat("─", inner+2)+"┘")
+}
+
+// helpOverlay returns the escapes that draw the help box centered on a screen
+// of the given size, cut to fit.
+func helpOverlay(rows, cols int) string {
+	box := helpBox()
+	top := max((rows-len(box))/2, 0)
+	left := max((cols-visibleWidth(box[0]))/2, 0)
+	var b strings.Builder
+	for i, row := range box[:min(len(box), rows)] {
+		fmt.Fprintf(&b, "\033[%d;%dH%s", top+i+1, left+1, truncateANSI(row, cols-left))
+	}
+	return b.String()
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HighlightRule colors every case-insensitive occurrence of a word or, for a
+// regex rule, the named capture groups of every match.
+type HighlightRule struct {
+	Word   string
+	Color  string
+	Groups map[string]string // Colors by capture group name, set for regex rules
+	Regex  bool              // Word is a regex written color-first, as "red = (error|fatal)"
+
+	// Options, written after the color as "word = color, option=value, ...".
+	MuteAfter int           // Hide matching lines after this many matches within Window
+	Window    time.Duration // Window for MuteAfter
+	Line      bool          // Color the whole line, not just the match
+	Alert     bool          // Matching lines are alerts, kept in the --alert-footer
+	Cooldown  time.Duration // Minimum time between reactions to this alert rule
+
+	source  string // Regex source, compiled on first use by regexp
+	literal string // Text every match contains, checked before compiling
+	fold    bool   // Whether literal matches case-insensitively
+	folded  string // Word case-folded, for --unicode-fold
+}
+
+// regexp returns the rule's compiled regex.
+func (r HighlightRule) regexp() *regexp.Regexp {
+	return compiledRegexes.get(r.source)
+}
+
+// findAll returns the byte ranges of a line that the rule matches.
+func (r HighlightRule) findAll(line string) [][]int {
+	if unicodeFold && r.Groups == nil && !r.Regex {
+		return foldedIndexes(line, r.folded)
+	}
+	if !mayContain(line, r.literal, r.fold) {
+		return nil
+	}
+	return r.regexp().FindAllStringIndex(line, -1)
+}
+
+// matches reports whether the rule matches anywhere in a line.
+func (r HighlightRule) matches(line string) bool {
+	if unicodeFold && r.Groups == nil && !r.Regex {
+		return len(foldedIndexes(line, r.folded)) > 0
+	}
+	if !mayContain(line, r.literal, r.fold) {
+		return false
+	}
+	return r.regexp().MatchString(line)
+}
+
+// defaultMuteWindow is the window for mute_after when none is given.
+const defaultMuteWindow = time.Minute
+
+// parseRule parses a "word = color[, option=value...]" config line.
+func parseRule(word, value string) (HighlightRule, error) {
+	parts := strings.Split(value, ",")
+	rule := newHighlightRule(word, parseColorSpec(strings.TrimSpace(parts[0])))
+	for _, option := range parts[1:] {
+		name, arg, _ := strings.Cut(option, "=")
+		name, arg = strings.TrimSpace(name), strings.TrimSpace(arg)
+		switch name {
+		case "mute_after":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("mute_after must be a positive number, got %q", arg)
+			}
+			rule.MuteAfter = n
+		case "line":
+			rule.Line = true
+		case "alert":
+			rule.Alert = true
+		case "cooldown":
+			d, err := time.ParseDuration(arg)
+			if err != nil || d <= 0 {
+				return rule, fmt.Errorf("cooldown must be a positive duration, got %q", arg)
+			}
+			rule.Cooldown = d
+		case "window":
+			d, err := time.ParseDuration(arg)
+			if err != nil || d <= 0 {
+				return rule, fmt.Errorf("window must be a positive duration, got %q", arg)
+			}
+			rule.Window = d
+		default:
+			return rule, fmt.Errorf("unknown option %q", name)
+		}
+	}
+	if rule.Cooldown > 0 && !rule.Alert {
+		return rule, fmt.Errorf("cooldown only applies to alert rules, add the alert option")
+	}
+	if rule.MuteAfter > 0 && rule.Window == 0 {
+		rule.Window = defaultMuteWindow
+	}
+	return rule, nil
+}
+
+// colorRegex returns the regex of a color-first rule value: a parenthesized
+// alternation such as "(error|fatal|panic)", a regex between slashes, or one
+// after a "regex " prefix. It returns false for a plain color value.
+func colorRegex(value string) (string, bool) {
+	switch {
+	case len(value) >= 2 && value[0] == '(' && value[len(value)-1] == ')':
+		return value, true
+	case len(value) >= 2 && value[0] == '/' && value[len(value)-1] == '/':
+		return value[1 : len(value)-1], true
+	case strings.HasPrefix(value, "regex "):
+		return strings.TrimSpace(strings.TrimPrefix(value, "regex ")), true
+	}
+	return "", false
+}
+
+// unterminatedColorRegex reports whether a color-first rule value starts a
+// regex that colorRegex doesn't accept, as in "red = (" or "red = /x", which
+// would otherwise be read as a word rule for the color's name.
+func unterminatedColorRegex(value string) bool {
+	_, ok := colorRegex(value)
+	return !ok && (strings.HasPrefix(value, "(") || strings.HasPrefix(value, "/"))
+}
+
+// isColorName reports whether name is one of the config's color names.
+func isColorName(name string) bool {
+	return name != "reset" && colorName(getColor(name)) == strings.ToLower(name)
+}
+
+// parseColorRule parses a color-first rule, "red = (error|fatal|panic|oom)",
+// which colors every case-insensitive match of one regex. The regex is used
+// as written rather than quoted.
+func parseColorRule(color, source string) (HighlightRule, error) {
+	re, err := checkRegex("(?i)" + source)
+	if err != nil {
+		return HighlightRule{}, err
+	}
+	rule := HighlightRule{Word: source, Color: parseColorSpec(color), Regex: true, source: "(?i)" + source}
+	rule.literal, rule.fold = requiredLiteral(re)
+	return rule, nil
+}
+
+// formatColorRule serializes a color-first rule as its config line.
+func formatColorRule(rule HighlightRule) string {
+	source := rule.Word
+	if !strings.HasPrefix(source, "(") || !strings.HasSuffix(source, ")") {
+		source = "/" + source + "/"
+	}
+	return colorSpecName(rule.Color) + " = " + source
+}
+
+// parseRegexRule parses a config line of the form
+//
+//	regex "(?P<method>\w+) (?P<path>\S+)" with method=cyan path=blue
+//
+// where each named group is colored by its mapped color and unmapped groups
+// are left alone.
+func parseRegexRule(line string) (HighlightRule, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "regex"))
+	source, n, ok := cutRegexLiteral(rest)
+	if !ok {
+		return HighlightRule{}, fmt.Errorf("expected a quoted regex")
+	}
+	re, err := checkRegex(source)
+	if err != nil {
+		return HighlightRule{}, err
+	}
+
+	mappings := strings.Fields(rest[n:])
+	if len(mappings) < 2 || mappings[0] != "with" {
+		return HighlightRule{}, fmt.Errorf("expected \"with group=color ...\" after the regex")
+	}
+	rule := HighlightRule{Word: source, Groups: make(map[string]string), source: source}
+	rule.literal, rule.fold = requiredLiteral(re)
+	for _, mapping := range mappings[1:] {
+		name, color, ok := strings.Cut(mapping, "=")
+		if !ok {
+			return rule, fmt.Errorf("expected group=color, got %q", mapping)
+		}
+		if name == "" || !slices.Contains(re.CapNames(), name) {
+			return rule, fmt.Errorf("the regex has no group named %q", name)
+		}
+		rule.Groups[name] = parseColorSpec(color)
+	}
+	return rule, nil
+}
+
+// cutRegexLiteral reads a double-quoted regex from the start of s, returning
+// it and the length consumed. Backslashes are kept for the regex, except in
+// \" which stands for a quote.
+func cutRegexLiteral(s string) (string, int, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", 0, false
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			return b.String(), i + 1, true
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, false
+}
+
+// groupSpans returns the spans of a regex rule's mapped capture groups.
+func groupSpans(rule HighlightRule, line string) []span {
+	if !mayContain(line, rule.literal, rule.fold) {
+		return nil
+	}
+	var spans []span
+	pattern := rule.regexp()
+	names := pattern.SubexpNames()
+	for _, loc := range pattern.FindAllStringSubmatchIndex(line, -1) {
+		for i, name := range names {
+			color, ok := rule.Groups[name]
+			if !ok || loc[2*i] < 0 || loc[2*i] == loc[2*i+1] {
+				continue
+			}
+			spans = append(spans, span{loc[2*i], loc[2*i+1], ruleColor(color)})
+		}
+	}
+	return spans
+}
+
+// formatRegexRule serializes a regex rule as its config line.
+func formatRegexRule(rule HighlightRule) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "regex \"%s\" with", strings.ReplaceAll(rule.Word, `"`, `\"`))
+	for _, name := range rule.regexp().SubexpNames() {
+		if color, ok := rule.Groups[name]; ok {
+			fmt.Fprintf(&b, " %s=%s", name, colorSpecName(color))
+		}
+	}
+	return b.String()
+}
+
+// formatRule serializes a rule as the value of its config line.
+func formatRule(rule HighlightRule) string {
+	parts := []string{colorSpecName(rule.Color)}
+	if rule.MuteAfter > 0 {
+		parts = append(parts, fmt.Sprintf("mute_after=%d", rule.MuteAfter), "window="+rule.Window.String())
+	}
+	if rule.Line {
+		parts = append(parts, "line")
+	}
+	if rule.Alert {
+		parts = append(parts, "alert")
+	}
+	if rule.Cooldown > 0 {
+		parts = append(parts, "cooldown="+rule.Cooldown.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// newHighlightRule compiles a rule for a literal word.
+func newHighlightRule(word, color string) HighlightRule {
+	return HighlightRule{
+		Word:    word,
+		Color:   color,
+		source:  "(?i)" + regexp.QuoteMeta(word),
+		literal: word,
+		fold:    true,
+		folded:  foldString(word),
+	}
+}
+
+// matchedRules returns the words of the highlight rules that match a line.
+func matchedRules(cfg Config, line string) []string {
+	var words []string
+	for _, rule := range cfg.Highlights {
+		if rule.matches(line) {
+			words = append(words, rule.Word)
+		}
+	}
+	return words
+}
+
+// span is a colored byte range [start, end) of a line.
+type span struct {
+	start, end int
+	color      string
+}
+
+// lineSpans finds the colored ranges of a line in precedence order: highlight
+// rules in config order, then auto-colored tokens, then the detected log level,
+// then sizes and durations, then bracket pairs.
+func lineSpans(line string, cfg Config) []span {
+	spans := ruleSpans(line, cfg)
+	if autoColorPattern != nil {
+		spans = append(spans, tokenSpans(line)...)
+	}
+	if levelsActive(cfg) {
+		if s, ok := levelSpan(cfg, line); ok {
+			spans = append(spans, s)
+		}
+	}
+	if smartUnits {
+		spans = append(spans, unitSpans(line)...)
+	}
+	if matchBrackets {
+		spans = append(spans, bracketSpans(line)...)
+	}
+	return spans
+}
+
+// ruleSpans finds the ranges of a line matched by the config's highlight
+// rules, in config order.
+func ruleSpans(line string, cfg Config) []span {
+	var spans []span
+	for _, rule := range cfg.Highlights {
+		if rule.Groups != nil {
+			spans = append(spans, groupSpans(rule, line)...)
+			continue
+		}
+		for _, loc := range rule.findAll(line) {
+			if loc[0] < loc[1] {
+				spans = append(spans, span{loc[0], loc[1], ruleColor(rule.Color)})
+			}
+		}
+	}
+	return spans
+}
+
+// resolveSpans drops every span that overlaps one earlier in the list, so the
+// earlier rule wins, and returns the rest ordered by position.
+func resolveSpans(spans []span) []span {
+	var kept []span
+	for _, s := range spans {
+		overlaps := false
+		for _, k := range kept {
+			if s.start < k.end && k.start < s.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept = append(kept, s)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].start < kept[j].start })
+	return kept
+}
+
+// highlightLine highlights matched keywords, under the color of the first
+// whole-line rule matching the line.
+//
+// Matching always runs against the original line, and the output is fully
+// determined by the config: when matches overlap, the rule listed first in
+// the config wins, and explicit rules win over level and unit coloring.
+func highlightLine(line string, cfg Config) styledLine {
+	return styledLine{text: line, spans: resolveSpans(lineSpans(line, cfg))}.wrap(lineColor(cfg, line))
+}
+
+// highlightText is highlightLine rendered with ANSI escapes, for the views
+// only drawn on a terminal.
+func highlightText(line string, cfg Config) string {
+	return highlightLine(line, cfg).ansi()
+}
+
+// lineColor returns the color of the first whole-line rule matching a line,
+// or "" if none does.
+func lineColor(cfg Config, line string) string {
+	for _, rule := range cfg.Highlights {
+		if rule.Line && rule.matches(line) {
+			return ruleColor(rule.Color)
+		}
+	}
+	return ""
+}
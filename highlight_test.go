@@ -0,0 +1,106 @@
+package main
+
+import "testing"
+
+// mustParseConfig parses a config for a test, failing it on errors.
+func mustParseConfig(t *testing.T, content string) Config {
+	t.Helper()
+	cfg, err := parseConfig(content)
+	if err != nil {
+		t.Fatalf("parseConfig(%q): %v", content, err)
+	}
+	return cfg
+}
+
+// TestHighlightPrecedence pins the exact output of overlapping rules: the rule
+// listed first wins, and explicit rules win over unit coloring.
+func TestHighlightPrecedence(t *testing.T) {
+	yellowBold := parseColorSpec("yellow+bold")
+	tests := []struct {
+		name       string
+		config     string
+		smartUnits bool
+		line       string
+		want       string
+	}{
+		{
+			name:   "single word, every occurrence, any case",
+			config: "error = red\n",
+			line:   "Error then error",
+			want:   Red + "Error" + Reset + " then " + Red + "error" + Reset,
+		},
+		{
+			name:   "adjacent matches of different rules",
+			config: "foo = red\nbar = blue\n",
+			line:   "foobar",
+			want:   Red + "foo" + Reset + Blue + "bar" + Reset,
+		},
+		{
+			name:   "longer rule listed first wins the overlap",
+			config: "timeout error = green\nerror = red\n",
+			line:   "a timeout error b",
+			want:   "a " + Green + "timeout error" + Reset + " b",
+		},
+		{
+			name:   "shorter rule listed first wins the overlap",
+			config: "error = red\ntimeout error = green\n",
+			line:   "a timeout error b",
+			want:   "a timeout " + Red + "error" + Reset + " b",
+		},
+		{
+			name:   "a partly overlapping later match is dropped whole",
+			config: "err = red\nerror code = blue\n",
+			line:   "error code",
+			want:   Red + "err" + Reset + "or code",
+		},
+		{
+			name:   "later rule still colors where the earlier one doesn't match",
+			config: "error = red\ncode = blue\n",
+			line:   "error code",
+			want:   Red + "error" + Reset + " " + Blue + "code" + Reset,
+		},
+		{
+			name:   "color-first regex and word rule in config order",
+			config: "red = (panic|oom)\noom killer = yellow\n",
+			line:   "oom killer",
+			want:   Red + "oom" + Reset + " killer",
+		},
+		{
+			name:   "regex groups compete with word rules by config order",
+			config: "regex \"(?P<method>GET|POST) (?P<path>\\S+)\" with method=cyan path=blue\nindex = red\n",
+			line:   "GET /index",
+			want:   Cyan + "GET" + Reset + " " + Blue + "/index" + Reset,
+		},
+		{
+			name:       "rules win over unit coloring",
+			config:     "204ms = magenta\n",
+			smartUnits: true,
+			line:       "took 204ms, then 2s",
+			want:       "took " + Magenta + "204ms" + Reset + ", then " + Red + "2s" + Reset,
+		},
+		{
+			name:   "whole-line color nests the word highlights",
+			config: "ERROR = red, line\nyellow+bold = timeout\n",
+			line:   "ERROR request timeout",
+			want:   Red + Red + "ERROR" + Reset + Red + " request " + yellowBold + "timeout" + Reset + Red + Reset,
+		},
+		{
+			name:   "first matching line rule colors the line",
+			config: "WARN = yellow, line\nERROR = red, line\n",
+			line:   "ERROR after WARN",
+			want:   Yellow + Red + "ERROR" + Reset + Yellow + " after " + Yellow + "WARN" + Reset + Yellow + Reset,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := smartUnits
+			smartUnits = tt.smartUnits
+			t.Cleanup(func() { smartUnits = saved })
+
+			cfg := mustParseConfig(t, tt.config)
+			if got := highlightText(tt.line, cfg); got != tt.want {
+				t.Errorf("highlightText(%q)\n got %q\nwant %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
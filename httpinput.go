@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Options for --url.
+var streamURL string
+var streamHeaders stringList
+
+// Reconnect backoff for --url.
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// parseHeaders checks the --header values, each "Name: value".
+func parseHeaders(values []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%q must look like Name: value", value)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(v))
+	}
+	return header, nil
+}
+
+// followURL streams lines from an HTTP endpoint, reconnecting with backoff
+// whenever the response ends or fails. Server-sent event streams yield the
+// data of each event; other responses are read line by line.
+func followURL(url string, header http.Header) {
+	delay := minReconnectDelay
+	for {
+		received, err := readURL(url, header)
+		if received {
+			delay = minReconnectDelay
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading --url:", err)
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// readURL makes one request and appends its lines until the response ends. It
+// reports whether any lines were received.
+func readURL(url string, header http.Header) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header = header.Clone()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New(resp.Status)
+	}
+
+	sse := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	received := false
+	scanner := bufio.NewScanner(decodeInput(resp.Body))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(scanRawLines)
+	for scanner.Scan() {
+		raw := scanner.Text()
+		if sse {
+			// Only data fields carry log lines; events, IDs, retries and
+			// comments are skipped.
+			data, ok := strings.CutPrefix(raw, "data:")
+			if !ok {
+				continue
+			}
+			raw = strings.TrimPrefix(data, " ")
+		}
+		received = true
+		appendRawLog("", raw)
+	}
+	return received, scanner.Err()
+}
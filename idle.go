@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// idleDashboard is how long the input must be quiet before the view is
+// replaced by a summary (--idle-dashboard). It applies outside interactive
+// mode, where nothing else would redraw the screen.
+var idleDashboard time.Duration
+
+// lastLineAt is when the latest line arrived, or when loggo started. Guarded
+// by logsMutex.
+var lastLineAt = time.Now()
+
+// isIdle reports whether the dashboard should be shown. The caller must hold
+// logsMutex.
+func isIdle() bool {
+	return idleDashboard > 0 && !interactive && time.Since(lastLineAt) >= idleDashboard
+}
+
+// watchIdle redraws once a second while idle, so the dashboard appears on
+// time and its clock stays current. New lines redraw the live view themselves.
+func watchIdle() {
+	for range time.Tick(time.Second) {
+		logsMutex.RLock()
+		idle := isIdle()
+		logsMutex.RUnlock()
+		if idle {
+			reprintLogs()
+		}
+	}
+}
+
+// writeDashboard writes the idle summary: how long the input has been quiet,
+// the buffer size, and the match counts per keyword. The caller must hold
+// logsMutex.
+func writeDashboard(w io.Writer) {
+	fmt.Fprintf(w, "%sloggo: no new lines for %s%s\n\n", Bold, time.Since(lastLineAt).Round(time.Second), Reset)
+	fmt.Fprintf(w, "  last line    %s\n", lastLineAt.Format("15:04:05"))
+	fmt.Fprintf(w, "  lines read   %d\n", linesTotal.value.Load())
+	fmt.Fprintf(w, "  buffered     %d\n", len(storedLogs))
+
+	counts := matchesTotal.snapshot()
+	if len(counts) == 0 {
+		return
+	}
+	keywords := make([]string, 0, len(counts))
+	for keyword := range counts {
+		keywords = append(keywords, keyword)
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if counts[keywords[i]] != counts[keywords[j]] {
+			return counts[keywords[i]] > counts[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+	fmt.Fprintf(w, "\n  %smatches%s\n", Bold, Reset)
+	for _, keyword := range keywords {
+		fmt.Fprintf(w, "  %8d  %s\n", counts[keyword], keyword)
+	}
+}
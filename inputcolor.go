@@ -0,0 +1,24 @@
+package main
+
+import "strings"
+
+// respectInputColor leaves lines that arrive already colored as they are,
+// without highlights (--respect-input-color). It is for filtering the output
+// of tools that color their own logs.
+var respectInputColor bool
+
+// hasSGR reports whether a line contains an SGR (color or attribute) escape.
+func hasSGR(line string) bool {
+	for i := strings.Index(line, "\033["); i >= 0; {
+		n := skipEscape(line[i:])
+		if line[i+n-1] == 'm' {
+			return true
+		}
+		next := strings.Index(line[i+n:], "\033[")
+		if next < 0 {
+			return false
+		}
+		i += n + next
+	}
+	return false
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// interactive enables the keyboard-driven viewer (--interactive).
+var interactive bool
+
+// keyBinding maps one or more keys to an action in interactive mode.
+type keyBinding struct {
+	keys   []string
+	help   string
+	action func()
+}
+
+// keyBindings lists every interactive key. It is filled in init to avoid an
+// initialization cycle with actions that refer back to it.
+var keyBindings []keyBinding
+
+func init() {
+	keyBindings = []keyBinding{
+		{[]string{"up", "k"}, "focus the previous line", func() { moveFocus(-1) }},
+		{[]string{"down", "j"}, "focus the next line", func() { moveFocus(1) }},
+		{[]string{"pgup"}, "page up", func() { moveFocus(-pageSize()) }},
+		{[]string{"pgdown"}, "page down", func() { moveFocus(pageSize()) }},
+		{[]string{"home", "g"}, "jump to the first line", func() { moveFocus(math.MinInt32) }},
+		{[]string{"end", "G"}, "follow the newest lines", followTail},
+		{[]string{"p"}, "pin or unpin the focused line", togglePin},
+		{[]string{"P"}, "clear all pins", clearPins},
+		{[]string{"S"}, "save the current view to a file", saveBuffer},
+		{[]string{"x"}, "expand or collapse lines hidden by --max-display", toggleExpanded},
+		{[]string{"e"}, "edit the config file in $EDITOR", editConfig},
+		{[]string{"v"}, "compare two filters side by side", toggleSplit},
+		{[]string{"/"}, "search, narrowing the view as you type", startSearch},
+		{[]string{"ctrl-s"}, "save the config as a named preset", savePreset},
+		{[]string{"o"}, "load a saved preset", loadPreset},
+		{[]string{"c", "ctrl-l"}, "clear the buffer and start fresh", clearBuffer},
+		{[]string{"?"}, "show this help", showHelp},
+		{[]string{"q"}, "quit", quitInteractive},
+	}
+}
+
+// keyInput carries decoded keys from the terminal to the dispatcher. Actions
+// run on the dispatcher goroutine and may read further keys from it directly.
+var keyInput chan string
+
+// quit is closed when the user quits the interactive viewer, once however
+// often they press q.
+var quit = make(chan struct{})
+var quitOnce sync.Once
+
+// View state, guarded by viewMutex.
+var viewMutex sync.Mutex
+var following = true
+var focusID uint64
+var visibleIDs []uint64 // IDs of the log lines in the last render, in display order
+var lastBodyRows = 1
+var promptLine string    // Prompt being typed into, shown instead of the status
+var statusMessage string // One-off message, cleared by the next key
+
+// savedTerminalState is the terminal mode to restore on exit, from stty -g.
+var savedTerminalState string
+
+// cbreakMode is the stty mode used while the viewer reads keys. Flow control
+// is off so that ctrl-s reaches the viewer.
+var cbreakMode = []string{"-icanon", "-echo", "-ixon", "min", "1"}
+
+// keysPaused is non-nil while key reading is paused; readKeys waits for it to
+// be closed. Guarded by keysMutex.
+var keysMutex sync.Mutex
+var keysPaused chan struct{}
+
+// startInteractive puts the terminal into cbreak mode and starts reading keys.
+func startInteractive() error {
+	if !isTerminal(os.Stdout) {
+		return errors.New("--interactive needs stdout to be a terminal")
+	}
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	tty = f
+
+	savedTerminalState, err = stty("-g")
+	if err != nil {
+		return fmt.Errorf("reading terminal state: %w", err)
+	}
+	if _, err := stty(cbreakMode...); err != nil {
+		return fmt.Errorf("setting terminal mode: %w", err)
+	}
+	fmt.Print(HideCursor)
+	atExit(func() {
+		stty(savedTerminalState)
+		fmt.Print(ShowCursor, "\n")
+	})
+
+	keyInput = make(chan string, 16)
+	go readKeys()
+	go func() {
+		for key := range keyInput {
+			handleKey(key)
+		}
+	}()
+	return nil
+}
+
+// readKeys decodes keypresses from the terminal into keyInput.
+func readKeys() {
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			keysMutex.Lock()
+			paused := keysPaused
+			keysMutex.Unlock()
+			if paused != nil {
+				<-paused
+			}
+			tty.SetReadDeadline(time.Time{})
+			continue
+		}
+		if err != nil {
+			return
+		}
+		for _, key := range decodeKeys(buf[:n]) {
+			keyInput <- key
+		}
+	}
+}
+
+// pauseKeys stops readKeys from consuming terminal input, so that a
+// subprocess can read it, until the returned function is called.
+func pauseKeys() (func(), error) {
+	resume := make(chan struct{})
+	keysMutex.Lock()
+	keysPaused = resume
+	keysMutex.Unlock()
+	if err := tty.SetReadDeadline(time.Now()); err != nil {
+		return nil, err
+	}
+	return func() {
+		keysMutex.Lock()
+		keysPaused = nil
+		keysMutex.Unlock()
+		close(resume)
+	}, nil
+}
+
+// escapeKeys names the escape sequences sent by special keys.
+var escapeKeys = map[string]string{
+	"\033[A": "up", "\033[B": "down", "\033[C": "right", "\033[D": "left",
+	"\033OA": "up", "\033OB": "down", "\033OC": "right", "\033OD": "left",
+	"\033[H": "home", "\033[F": "end", "\033[1~": "home", "\033[4~": "end",
+	"\033[5~": "pgup", "\033[6~": "pgdown", "\033[3~": "delete",
+}
+
+// decodeKeys splits raw terminal input into key names. Printable characters
+// are returned as themselves, control characters as "ctrl-x".
+func decodeKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		s := string(input)
+		if input[0] == '\033' {
+			n := skipEscape(s)
+			if len(s) >= 3 && s[1] == 'O' {
+				n = 3 // SS3, sent for arrow keys in application mode
+			}
+			if n > 1 {
+				if name, ok := escapeKeys[s[:n]]; ok {
+					keys = append(keys, name)
+				}
+				input = input[n:]
+				continue
+			}
+			keys = append(keys, "esc")
+			input = input[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRune(input)
+		input = input[size:]
+		switch {
+		case r == '\r' || r == '\n':
+			keys = append(keys, "enter")
+		case r == 0x7f || r == 0x08:
+			keys = append(keys, "backspace")
+		case r == '\t':
+			keys = append(keys, "tab")
+		case r < 0x20:
+			keys = append(keys, "ctrl-"+string(rune('a'+r-1)))
+		default:
+			keys = append(keys, string(r))
+		}
+	}
+	return keys
+}
+
+// handleKey runs the action bound to a key, if any.
+func handleKey(key string) {
+	viewMutex.Lock()
+	hadMessage := statusMessage != ""
+	statusMessage = ""
+	viewMutex.Unlock()
+	if hadMessage {
+		reprintLogs()
+	}
+
+	for _, binding := range keyBindings {
+		for _, k := range binding.keys {
+			if k == key {
+				binding.action()
+				return
+			}
+		}
+	}
+}
+
+// showMessage displays a message in the status bar until the next key.
+func showMessage(format string, args ...any) {
+	viewMutex.Lock()
+	statusMessage = fmt.Sprintf(format, args...)
+	viewMutex.Unlock()
+	reprintLogs()
+}
+
+// prompt asks a question in the status bar and reads a line of input. It
+// returns false if the user cancels with Esc. It must be called from a key
+// action, since it reads the following keys itself.
+func prompt(question string) (string, bool) {
+	return promptWith(question, nil)
+}
+
+// promptWith is prompt with a callback run after each edit of the answer,
+// which then takes over reprinting the view.
+func promptWith(question string, onEdit func(answer string)) (string, bool) {
+	var answer []rune
+	for {
+		viewMutex.Lock()
+		promptLine = question + string(answer)
+		viewMutex.Unlock()
+		if onEdit != nil {
+			onEdit(string(answer))
+		} else {
+			reprintLogs()
+		}
+
+		key := <-keyInput
+		switch {
+		case key == "enter" || key == "esc":
+			viewMutex.Lock()
+			promptLine = ""
+			viewMutex.Unlock()
+			reprintLogs()
+			return string(answer), key == "enter"
+		case key == "backspace":
+			if len(answer) > 0 {
+				answer = answer[:len(answer)-1]
+			}
+		case utf8.RuneCountInString(key) == 1:
+			answer = append(answer, []rune(key)...)
+		}
+	}
+}
+
+// confirm asks a yes/no question in the status bar.
+func confirm(question string) bool {
+	answer, ok := prompt(question + " (y/n) ")
+	return ok && strings.HasPrefix(strings.ToLower(answer), "y")
+}
+
+// pageSize returns how many lines a page up or down moves.
+func pageSize() int {
+	viewMutex.Lock()
+	defer viewMutex.Unlock()
+	return max(lastBodyRows-1, 1)
+}
+
+// indexOfID returns the position of a log line ID in the visible lines or, if
+// it is no longer shown, of the first line that arrived after it.
+func indexOfID(ids []uint64, id uint64) int {
+	for i, visible := range ids {
+		if visible == id {
+			return i
+		}
+	}
+	for i, visible := range ids {
+		if visible > id {
+			return i
+		}
+	}
+	return len(ids) - 1
+}
+
+// keepFocus moves the focus to the nearest visible line when the focused line
+// is no longer shown, as after a config reload re-filters the buffer, so the
+// view stays where the user was reading rather than jumping. The caller must
+// hold logsMutex and viewMutex, with visibleIDs up to date.
+func keepFocus() {
+	if len(visibleIDs) == 0 || slices.Contains(visibleIDs, focusID) {
+		return
+	}
+	// Lines are shown in buffer order, which isn't ID order with --time-merge.
+	position := make(map[uint64]int, len(storedLogs))
+	for i, entry := range storedLogs {
+		position[entry.ID] = i
+	}
+	at, ok := position[focusID]
+	if !ok {
+		return // Evicted: indexOfID falls back to the next newer line
+	}
+	best, bestDistance := focusID, math.MaxInt
+	for _, id := range visibleIDs {
+		p, ok := position[id]
+		if !ok {
+			continue
+		}
+		// Prefer the following line on a tie, as the one that took its place.
+		distance := 2 * (p - at)
+		if distance < 0 {
+			distance = -distance + 1
+		}
+		if distance < bestDistance {
+			best, bestDistance = id, distance
+		}
+	}
+	focusID = best
+}
+
+// moveFocus moves the focused line by delta visible lines and stops following.
+func moveFocus(delta int) {
+	viewMutex.Lock()
+	if len(visibleIDs) > 0 {
+		i := len(visibleIDs) - 1
+		if !following {
+			i = indexOfID(visibleIDs, focusID)
+		}
+		i = min(max(i+delta, 0), len(visibleIDs)-1)
+		focusID = visibleIDs[i]
+		following = false
+	}
+	viewMutex.Unlock()
+	reprintLogs()
+}
+
+// followTail focuses the newest line and keeps following new ones.
+func followTail() {
+	viewMutex.Lock()
+	following = true
+	viewMutex.Unlock()
+	reprintLogs()
+}
+
+// clearConfirmLines is the buffer size above which clearBuffer asks first.
+const clearConfirmLines = 1000
+
+// clearBuffer drops every stored line, keeping the inputs attached so new
+// lines start a fresh view. Pinned lines are kept.
+func clearBuffer() {
+	logsMutex.RLock()
+	n := len(storedLogs)
+	logsMutex.RUnlock()
+	if n > clearConfirmLines && !confirm(fmt.Sprintf("Clear %d lines?", n)) {
+		return
+	}
+
+	logsMutex.Lock()
+	storedLogs = nil
+	recentAlerts = nil
+	clear(topCounts)
+	topTotal = 0
+	measureOpen = false
+	logsMutex.Unlock()
+	viewMutex.Lock()
+	following = true
+	viewMutex.Unlock()
+	showMessage("Cleared %d lines", n)
+}
+
+// quitInteractive ends the interactive session.
+func quitInteractive() {
+	quitOnce.Do(func() { close(quit) })
+}
+
+// drawView renders the visible part of the log view with the pinned lines
+// above it and a status bar below. The caller must hold logsMutex.
+func drawView(w io.Writer, lines []displayLine) {
+	viewMutex.Lock()
+	split := splitView
+	viewMutex.Unlock()
+	if split {
+		drawSplit(w)
+		return
+	}
+	rows, cols := terminalSize()
+
+	var footer []string
+	for _, row := range alertRows() {
+		footer = append(footer, truncateANSI(row.ansi(), cols))
+	}
+	viewMutex.Lock()
+	pinned := pinnedRows(rows/3, cols)
+	bodyRows := max(rows-len(pinned)-len(footer)-1, 1)
+	lastBodyRows = bodyRows
+
+	visibleIDs = visibleIDs[:0]
+	var logRows []int
+	for i, line := range lines {
+		if line.isLog {
+			visibleIDs = append(visibleIDs, line.id)
+			logRows = append(logRows, i)
+		}
+	}
+	if following && len(visibleIDs) > 0 {
+		focusID = visibleIDs[len(visibleIDs)-1]
+	} else if !following {
+		keepFocus()
+	}
+
+	// Keep the focused line on screen, centering it when scrolled back.
+	focusRow := -1
+	if len(logRows) > 0 {
+		focusRow = logRows[indexOfID(visibleIDs, focusID)]
+	}
+	top := max(len(lines)-bodyRows, 0)
+	if !following {
+		top = min(max(focusRow-bodyRows/2, 0), top)
+	}
+	status := statusText(len(visibleIDs))
+	help := helpVisible
+	barColor := Reverse
+	if message := configErrorMessage(); message != "" && promptLine == "" && statusMessage == "" {
+		status, barColor = " "+message, Reverse+Red
+	}
+	viewMutex.Unlock()
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for _, row := range pinned {
+		b.WriteString(ClearLine + row + "\n")
+	}
+	for r := 0; r < bodyRows; r++ {
+		b.WriteString(ClearLine)
+		if i := top + r; i < len(lines) {
+			gutter := "  "
+			if i == focusRow && !following {
+				gutter = Reverse + ">" + Reset + " "
+			}
+			b.WriteString(gutter + truncateANSI(lines[i].ansi(), cols-2))
+		}
+		b.WriteString("\n")
+	}
+	for _, row := range footer {
+		b.WriteString(ClearLine + row + "\n")
+	}
+	b.WriteString(ClearLine + barColor + truncateANSI(fmt.Sprintf("%-*s", cols, status), cols) + Reset)
+	if help {
+		b.WriteString(helpOverlay(rows, cols))
+	}
+	fmt.Fprint(w, b.String())
+}
+
+// statusText describes the view state for the status bar. The caller must hold viewMutex.
+func statusText(total int) string {
+	if promptLine != "" {
+		return " " + promptLine
+	}
+	if statusMessage != "" {
+		return " " + statusMessage
+	}
+	position := "FOLLOW"
+	if !following {
+		position = fmt.Sprintf("line %d/%d", indexOfID(visibleIDs, focusID)+1, total)
+	}
+	parts := []string{position, fmt.Sprintf("%d lines", total)}
+	if len(pinnedEntries) > 0 {
+		parts = append(parts, fmt.Sprintf("%d pinned", len(pinnedEntries)))
+	}
+	if searchTerm != "" {
+		parts = append(parts, "search: "+searchTerm)
+	}
+	if rate := renderRate(); rate != "" {
+		parts = append(parts, rate)
+	}
+	return " " + strings.Join(parts, " | ")
+}
@@ -0,0 +1,63 @@
+//go:build kafka
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Options for --kafka, only present in binaries built with -tags kafka.
+var kafkaTarget string
+var kafkaGroup string
+
+func init() {
+	flag.StringVar(&kafkaTarget, "kafka", "", "Consume lines from a Kafka topic, as broker:9092[,broker2:9092]/topic")
+	flag.StringVar(&kafkaGroup, "kafka-group", "loggo", "Kafka consumer group, so restarts resume where they left off")
+	optionalInputs = append(optionalInputs, kafkaInput)
+}
+
+// kafkaInput returns a reader for the --kafka topic, or nil if none was given.
+func kafkaInput() func() {
+	if kafkaTarget == "" {
+		return nil
+	}
+	brokers, topic, ok := strings.Cut(kafkaTarget, "/")
+	if !ok || brokers == "" || topic == "" {
+		fmt.Fprintln(os.Stderr, "Error: --kafka must look like broker:9092/topic")
+		os.Exit(2)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: strings.Split(brokers, ","),
+		Topic:   topic,
+		GroupID: kafkaGroup,
+	})
+	atExit(func() { reader.Close() })
+
+	return func() {
+		for {
+			// With a consumer group, reading a message also commits its offset.
+			message, err := reader.ReadMessage(context.Background())
+			if err != nil {
+				// The reader returns io.EOF once it has been closed at shutdown.
+				if !errors.Is(err, io.EOF) {
+					fmt.Fprintln(os.Stderr, "Error reading from Kafka:", err)
+				}
+				return
+			}
+			// Each line is recorded with a newline, so messages that end
+			// without one stay separate lines in a recording.
+			for _, line := range strings.Split(strings.TrimRight(decodeBytes(message.Value), "\n"), "\n") {
+				appendRawLog("", line+"\n")
+			}
+		}
+	}
+}
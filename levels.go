@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Level is one severity in an ordered log level scheme, lowest first.
+type Level struct {
+	Names []string // Name and aliases, matched case-insensitively
+	Color string
+}
+
+// defaultLevelSpec is used unless the config sets "levels".
+const defaultLevelSpec = "trace, debug, info, warn|warning, error|err, fatal|critical|panic"
+
+// levelLadder colors levels from least to most severe.
+var levelLadder = []string{Blue, Cyan, Green, Yellow, Red, Magenta}
+
+var defaultLevels = parseLevels(defaultLevelSpec)
+var defaultLevelPattern = levelPattern(defaultLevels)
+
+// minLevel hides lines below this severity (--min-level).
+var minLevel string
+
+// parseLevels parses a comma-separated list of levels, lowest severity first.
+// Aliases for one level are separated by "|".
+func parseLevels(spec string) []Level {
+	var levels []Level
+	for _, group := range strings.Split(spec, ",") {
+		var names []string
+		for _, name := range strings.Split(group, "|") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			levels = append(levels, Level{Names: names})
+		}
+	}
+
+	// Spread the color ladder across however many levels there are.
+	for i := range levels {
+		step := 0
+		if len(levels) > 1 {
+			step = i * (len(levelLadder) - 1) / (len(levels) - 1)
+		}
+		levels[i].Color = levelLadder[step]
+	}
+	return levels
+}
+
+// levelPattern builds a regex matching any level name as a whole word.
+func levelPattern(levels []Level) *regexp.Regexp {
+	var names []string
+	for _, level := range levels {
+		for _, name := range level.Names {
+			names = append(names, regexp.QuoteMeta(name))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(names, "|") + `)\b`)
+}
+
+// levelsActive reports whether level filtering and coloring are in use.
+func levelsActive(cfg Config) bool {
+	return minLevel != "" || cfg.Levels != nil
+}
+
+// configLevels returns the level scheme and matcher in effect for a config.
+func configLevels(cfg Config) ([]Level, *regexp.Regexp) {
+	if cfg.Levels != nil {
+		return cfg.Levels, cfg.levelPattern
+	}
+	return defaultLevels, defaultLevelPattern
+}
+
+// levelRank returns the severity index of a level name, or -1 if unknown.
+func levelRank(levels []Level, name string) int {
+	for i, level := range levels {
+		for _, alias := range level.Names {
+			if strings.EqualFold(alias, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// detectLevel finds the first level name in a line, returning its severity
+// index and location, or -1 if the line has no recognizable level.
+func detectLevel(cfg Config, line string) (int, []int) {
+	levels, pattern := configLevels(cfg)
+	if pattern == nil {
+		return -1, nil
+	}
+	loc := pattern.FindStringIndex(line)
+	if loc == nil {
+		return -1, nil
+	}
+	return levelRank(levels, line[loc[0]:loc[1]]), loc
+}
+
+// belowMinLevel reports whether a line's detected level is below --min-level.
+// Lines without a recognizable level are never hidden.
+func belowMinLevel(cfg Config, line string) bool {
+	if minLevel == "" {
+		return false
+	}
+	levels, _ := configLevels(cfg)
+	threshold := levelRank(levels, minLevel)
+	if threshold < 0 {
+		return false
+	}
+	rank, _ := detectLevel(cfg, line)
+	return rank >= 0 && rank < threshold
+}
+
+// levelSpan returns the colored range of the detected level name in a line.
+func levelSpan(cfg Config, line string) (span, bool) {
+	rank, loc := detectLevel(cfg, line)
+	if rank < 0 {
+		return span{}, false
+	}
+	levels, _ := configLevels(cfg)
+	return span{loc[0], loc[1], levels[rank].Color}, true
+}
+
+// checkMinLevel warns when --min-level names a level the config doesn't define.
+func checkMinLevel(cfg Config) {
+	if minLevel == "" {
+		return
+	}
+	if levels, _ := configLevels(cfg); levelRank(levels, minLevel) < 0 {
+		fmt.Fprintf(os.Stderr, "Warning: unknown level %q for --min-level, showing all levels\n", minLevel)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lineProcessRestartDelay is the minimum time between restarts of a failed
+// helper command. It doubles with each failure in a row, up to
+// lineProcessMaxRestartDelay.
+const lineProcessRestartDelay = time.Second
+const lineProcessMaxRestartDelay = 30 * time.Second
+
+// lineProcessTimeout is how long a helper command has to answer a line before
+// it counts as failed. A command that buffers its output would otherwise
+// never answer, freezing the view.
+const lineProcessTimeout = 2 * time.Second
+
+// lineProcessStopTimeout is how long a helper command has to exit at
+// shutdown once its stdin is closed, before it is killed.
+const lineProcessStopTimeout = time.Second
+
+// errStopped is returned for queries made after shutdown has begun.
+var errStopped = errors.New("shutting down")
+
+// lineProcess is a long-running helper command that answers each line written
+// to its stdin with one line on stdout, as used by --filter-cmd and
+// --enrich-cmd. The process is started on the first query, and restarted
+// with a growing delay when it fails.
+//
+// Queries must be serialized by the caller. The process state has its own
+// mutex, so that it can be stopped while a query waits for its answer.
+type lineProcess struct {
+	name string // For error messages, like "filter command"
+	args []string
+
+	failures int       // Failures in a row, for backing off restarts
+	lastFail time.Time // Guarded by the caller, like failures
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   *os.File
+	stdout  *os.File
+	reader  *bufio.Reader
+	exited  chan struct{} // Closed once the process has exited
+	stopped bool          // Set at shutdown, so the process isn't restarted
+}
+
+// newLineProcess creates a helper for the given command line.
+func newLineProcess(name, command string) *lineProcess {
+	return &lineProcess{name: name, args: strings.Fields(command)}
+}
+
+// ask sends a line and returns the answer. It returns false if the process
+// isn't running and can't be restarted yet, or fails to answer in time;
+// errors are reported on stderr.
+func (p *lineProcess) ask(line string) (string, bool) {
+	if !p.running() {
+		if time.Since(p.lastFail) < p.restartDelay() {
+			return "", false
+		}
+		if err := p.start(); err != nil {
+			if err != errStopped {
+				fmt.Fprintf(os.Stderr, "Error starting %s: %v\n", p.name, err)
+			}
+			p.failures++
+			p.lastFail = time.Now()
+			return "", false
+		}
+	}
+
+	reply, err := p.query(line)
+	if err != nil {
+		p.fail(err)
+		return "", false
+	}
+	p.failures = 0
+	return reply, true
+}
+
+// fail reports an error from the process and tears it down, as when it
+// answers with nonsense.
+func (p *lineProcess) fail(err error) {
+	// A process stopped at shutdown is already gone, and that's no error.
+	if p.running() {
+		fmt.Fprintf(os.Stderr, "Error from %s: %v\n", p.name, err)
+	}
+	p.kill()
+	p.failures++
+	p.lastFail = time.Now()
+}
+
+// restartDelay is how long to wait after the last failure before starting
+// the process again.
+func (p *lineProcess) restartDelay() time.Duration {
+	return min(lineProcessRestartDelay<<min(max(p.failures-1, 0), 5), lineProcessMaxRestartDelay)
+}
+
+// running reports whether the process is up.
+func (p *lineProcess) running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cmd != nil
+}
+
+// start launches the process. Its pipes are created directly, rather than
+// with cmd.StdinPipe, so that reads and writes can have deadlines.
+func (p *lineProcess) start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return errStopped
+	}
+
+	stdinRead, stdinWrite, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	stdoutRead, stdoutWrite, err := os.Pipe()
+	if err != nil {
+		stdinRead.Close()
+		stdinWrite.Close()
+		return err
+	}
+	cmd := exec.Command(p.args[0], p.args[1:]...)
+	cmd.Stdin = stdinRead
+	cmd.Stdout = stdoutWrite
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	// The child has its own copies of these ends now.
+	stdinRead.Close()
+	stdoutWrite.Close()
+	if err != nil {
+		stdinWrite.Close()
+		stdoutRead.Close()
+		return err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	p.cmd = cmd
+	p.stdin = stdinWrite
+	p.stdout = stdoutRead
+	p.reader = bufio.NewReader(stdoutRead)
+	p.exited = exited
+	return nil
+}
+
+// query writes a line and reads the answer, which must arrive within
+// lineProcessTimeout.
+func (p *lineProcess) query(line string) (string, error) {
+	p.mu.Lock()
+	if p.cmd == nil {
+		p.mu.Unlock()
+		return "", errStopped
+	}
+	stdin, stdout, reader := p.stdin, p.stdout, p.reader
+	p.mu.Unlock()
+
+	deadline := time.Now().Add(lineProcessTimeout)
+	stdin.SetWriteDeadline(deadline)
+	stdout.SetReadDeadline(deadline)
+	if _, err := io.WriteString(stdin, line+"\n"); err != nil {
+		return "", err
+	}
+	reply, err := reader.ReadString('\n')
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return "", fmt.Errorf("no answer within %v", lineProcessTimeout)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(reply, "\r\n"), nil
+}
+
+// kill tears down the process, if it is running. It doesn't wait for a query
+// in progress, which fails once the process is gone.
+func (p *lineProcess) kill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == nil {
+		return
+	}
+	p.cmd.Process.Kill()
+	<-p.exited
+	p.closeProcess()
+}
+
+// stop closes the process's stdin and waits for it to exit, killing it if it
+// doesn't within lineProcessStopTimeout. The process isn't restarted after.
+func (p *lineProcess) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	if p.cmd == nil {
+		return
+	}
+	p.stdin.Close()
+	select {
+	case <-p.exited:
+	case <-time.After(lineProcessStopTimeout):
+		p.cmd.Process.Kill()
+		<-p.exited
+	}
+	p.closeProcess()
+}
+
+// closeProcess closes the pipes of an exited process. The caller must hold p.mu.
+func (p *lineProcess) closeProcess() {
+	p.stdin.Close()
+	p.stdout.Close()
+	p.cmd = nil
+}
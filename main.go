@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -14,49 +16,189 @@ import (
 
 // ANSI color codes for highlighting and clearing the screen.
 const (
-	Reset       = "\033[0m"
-	Red         = "\033[31m"
-	Green       = "\033[32m"
-	Yellow      = "\033[33m"
-	Blue        = "\033[34m"
-	Magenta     = "\033[35m"
-	Cyan        = "\033[36m"
+	Reset   = "\033[0m"
+	Bold    = "\033[1m"
+	Dim     = "\033[2m"
+	Red     = "\033[31m"
+	Green   = "\033[32m"
+	Yellow  = "\033[33m"
+	Blue    = "\033[34m"
+	Magenta = "\033[35m"
+	Cyan    = "\033[36m"
+	Gray    = "\033[90m"
+
+	BrightRed     = "\033[91m"
+	BrightGreen   = "\033[92m"
+	BrightYellow  = "\033[93m"
+	BrightBlue    = "\033[94m"
+	BrightMagenta = "\033[95m"
+	BrightCyan    = "\033[96m"
+
 	ClearScreen = "\033[H\033[2J"
 )
 
 // Config holds filtering and multiple highlighting rules.
 type Config struct {
 	Filter     string
-	Highlights map[string]string // Map of words to highlight with their colors
+	Highlights []HighlightRule // Highlight rules in config file order
+	Levels     []Level         // Log levels, lowest severity first; nil uses the defaults
+
+	levelPattern *regexp.Regexp
+}
+
+// LogEntry is a stored log line along with when it was seen.
+type LogEntry struct {
+	ID      uint64 // Unique, increasing in arrival order
+	Text    string
+	Source  string // Name of the input the line came from, empty for a single input
+	Arrived time.Time
+	Time    time.Time // Timestamp parsed from the line, zero if none was found
+
+	FirstSeen bool   // The line is the first to contain one of the highlight keywords
+	Muted     string // Word of the mute_after rule that suppresses the line, if any
+	New       bool   // The line doesn't appear in the --baseline file
+	Group     string // Color of a --window-match rule matching across this and nearby lines
+
+	Measured bool          // The line ends a --measure pair
+	Elapsed  time.Duration // Time since the pair's start line
 }
 
-// Mutexes for thread-safe access to config and logs.
+// setHighlight adds a highlight rule, or replaces the rule if the word or
+// regex already has one.
+func (c *Config) setHighlight(rule HighlightRule) {
+	for i := range c.Highlights {
+		existing := c.Highlights[i]
+		if existing.Word == rule.Word && (existing.Groups == nil) == (rule.Groups == nil) && existing.Regex == rule.Regex {
+			c.Highlights[i] = rule
+			return
+		}
+	}
+	c.Highlights = append(c.Highlights, rule)
+}
+
+// displayLine is one rendered row of the log view.
+type displayLine struct {
+	styledLine
+	id    uint64 // ID of the log entry shown, if isLog
+	isLog bool   // False for separators and other decorations
+}
+
+// Mutexes for thread-safe access to config and logs, and to keep concurrent
+// reprints from interleaving.
 var configMutex sync.RWMutex
 var logsMutex sync.RWMutex
+var renderMutex sync.Mutex
 
 var currentConfig Config
-var storedLogs []string
+var storedLogs []LogEntry
+var nextLogID uint64
 var lastConfigContent string
 
-// highlightText highlights matched keywords using ANSI escape codes.
-func highlightText(line string, highlights map[string]string) string {
-	for word, color := range highlights {
-		re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(word))
-		line = re.ReplaceAllString(line, color+word+Reset)
+// loadMutex serializes config loads, so the poller and an interactive edit
+// don't both apply the same change.
+var loadMutex sync.Mutex
+
+// configFile is the path of the configuration file (--config or --preset).
+// Guarded by configMutex.
+var configFile string
+
+// focusMode dims lines that don't pass the filters instead of hiding them (--focus).
+var focusMode bool
+
+// filterLine reports whether a line passes every filter, along with the line
+// to display, which an external filter may have rewritten.
+func filterLine(cfg Config, line string) (bool, string) {
+	keep, line, _ := checkFilters(cfg, line)
+	return keep, line
+}
+
+// checkFilters is filterLine that also says which filter dropped the line, or
+// what let it through, for --explain.
+func checkFilters(cfg Config, line string) (bool, string, string) {
+	var reasons []string
+	if !containsFold(line, cfg.Filter) {
+		return false, line, fmt.Sprintf("doesn't contain filter %q", cfg.Filter)
+	}
+	if cfg.Filter != "" {
+		reasons = append(reasons, fmt.Sprintf("contains filter %q", cfg.Filter))
 	}
-	return line
+	if belowMinLevel(cfg, line) {
+		return false, line, "level is below --min-level " + minLevel
+	}
+	if queryRejects(line) {
+		return false, line, "rejected by --query"
+	}
+	if fieldFiltersReject(line) {
+		return false, line, "rejected by --field-filter"
+	}
+
+	// Consult the external filter for decisions the built-in filter can't express.
+	if externalFilter != nil {
+		keep, rewritten := externalFilter.decide(line)
+		if !keep {
+			return false, line, "dropped by --filter-cmd"
+		}
+		if rewritten != line {
+			reasons = append(reasons, "rewritten by --filter-cmd")
+		}
+		line = rewritten
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "no filters apply")
+	}
+	return true, line, strings.Join(reasons, "; ")
 }
 
 // filterAndHighlight applies the current configuration to format a log line.
-func filterAndHighlight(line string) string {
+// It returns false for lines that don't pass the filters, unless focus mode
+// shows them dimmed.
+func filterAndHighlight(line string) (styledLine, bool) {
 	configMutex.RLock()
 	cfg := currentConfig
 	configMutex.RUnlock()
 
-	if strings.Contains(strings.ToLower(line), strings.ToLower(cfg.Filter)) {
-		return highlightText(line, cfg.Highlights)
+	if matchTrimmed {
+		line = trimLine(line)
 	}
-	return ""
+	if matchProjected {
+		line = projectLine(line)
+	}
+	project := len(columns) > 0 && !matchProjected
+	keep, line := filterLine(cfg, line)
+	// The prefix is dropped once: up front with --match-trimmed, else after
+	// highlighting.
+	n := 0
+	if !matchTrimmed {
+		n = prefixLength(line)
+	}
+	if !keep {
+		if focusMode {
+			text := line[n:]
+			if project {
+				text = projectLine(text)
+			}
+			return styledText(text, Dim+Gray), true
+		}
+		return styledLine{}, false
+	}
+	// Highlight the full line so matches don't depend on the trimming or the
+	// column selection, then drop the prefix and project.
+	if respectInputColor && hasSGR(line) {
+		if project {
+			return inputStyled(projectLine(line[n:])), true
+		}
+		return inputStyled(line[n:]), true
+	}
+	if n == 0 && !project {
+		return highlightLine(line, cfg), true
+	}
+	spans := shiftSpans(resolveSpans(lineSpans(line, cfg)), n)
+	text := styledLine{text: line[n:], spans: spans}
+	if project {
+		text = projectSpans(line[n:], spans)
+	}
+	return text.wrap(lineColor(cfg, line)), true
 }
 
 // getColor returns the ANSI color code for a given color name.
@@ -79,26 +221,41 @@ func getColor(color string) string {
 	}
 }
 
-// loadConfig reads the config file and updates the global configuration.
-func loadConfig(configPath string) bool {
-	content, err := ioutil.ReadFile(configPath)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading config file:", err)
-		return false
-	}
-
-	// Compare with the last config content to avoid unnecessary reloads.
-	newContent := string(content)
-	if newContent == lastConfigContent {
-		return false
+// colorName returns the config name for an ANSI color code, the inverse of getColor.
+func colorName(code string) string {
+	switch code {
+	case Red:
+		return "red"
+	case Green:
+		return "green"
+	case Yellow:
+		return "yellow"
+	case Blue:
+		return "blue"
+	case Magenta:
+		return "magenta"
+	case Cyan:
+		return "cyan"
+	default:
+		return "reset"
 	}
-	lastConfigContent = newContent
+}
 
-	newConfig := Config{Highlights: make(map[string]string)}
-	scanner := bufio.NewScanner(strings.NewReader(newContent))
+// parseConfig parses config file content into a Config.
+func parseConfig(content string) (Config, error) {
+	newConfig := Config{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
 
-	for scanner.Scan() {
+	for n := 1; scanner.Scan(); n++ {
 		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "regex ") {
+			rule, err := parseRegexRule(strings.TrimSpace(line))
+			if err != nil {
+				return newConfig, fmt.Errorf("line %d: regex rule: %v", n, err)
+			}
+			newConfig.setHighlight(rule)
+			continue
+		}
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
 			continue
@@ -108,51 +265,329 @@ func loadConfig(configPath string) bool {
 		switch key {
 		case "filter":
 			newConfig.Filter = value
+		case "levels":
+			newConfig.Levels = parseLevels(value)
+			newConfig.levelPattern = levelPattern(newConfig.Levels)
 		default:
+			if source, ok := colorRegex(value); ok && isColorSpec(key) {
+				rule, err := parseColorRule(key, source)
+				if err != nil {
+					return newConfig, fmt.Errorf("line %d: %s rule: %v", n, key, err)
+				}
+				newConfig.setHighlight(rule)
+				continue
+			}
+			if isColorSpec(key) && unterminatedColorRegex(value) {
+				return newConfig, fmt.Errorf("line %d: %s rule: unterminated regex %q, expected (...) or /.../", n, key, value)
+			}
+			// A color spec with attributes, as in "red+blink = FATAL", can't be
+			// a word, so the value is the word.
+			if strings.Contains(key, "+") && isColorSpec(key) {
+				newConfig.setHighlight(newHighlightRule(value, parseColorSpec(key)))
+				continue
+			}
 			// Assume the key is a word to highlight, and value is its color.
-			newConfig.Highlights[key] = getColor(value)
+			rule, err := parseRule(key, value)
+			if err != nil {
+				return newConfig, fmt.Errorf("line %d: rule %q: %v", n, key, err)
+			}
+			newConfig.setHighlight(rule)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "Error parsing config file:", err)
-		return false
+	return newConfig, scanner.Err()
+}
+
+// formatConfig serializes a Config in the config file format, so that
+// parseConfig(formatConfig(cfg)) yields an equivalent config.
+func formatConfig(cfg Config) string {
+	var b strings.Builder
+	if cfg.Filter != "" {
+		fmt.Fprintf(&b, "filter = %s\n", cfg.Filter)
+	}
+	if cfg.Levels != nil {
+		var groups []string
+		for _, level := range cfg.Levels {
+			groups = append(groups, strings.Join(level.Names, "|"))
+		}
+		fmt.Fprintf(&b, "levels = %s\n", strings.Join(groups, ", "))
+	}
+	for _, rule := range cfg.Highlights {
+		if rule.Groups != nil {
+			fmt.Fprintln(&b, formatRegexRule(rule))
+			continue
+		}
+		if rule.Regex {
+			fmt.Fprintln(&b, formatColorRule(rule))
+			continue
+		}
+		fmt.Fprintf(&b, "%s = %s\n", rule.Word, formatRule(rule))
+	}
+	return b.String()
+}
+
+// loadConfig reads the config file and updates the global configuration. It
+// reports whether the config changed; on error the previous config is kept.
+func loadConfig(configPath string) (bool, error) {
+	loadMutex.Lock()
+	defer loadMutex.Unlock()
+
+	content, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return false, err
+	}
+
+	// Compare with the content of the config in use to avoid unnecessary
+	// reloads. A file that failed to parse is parsed again on every poll, so
+	// its error stays reported until it is fixed.
+	newContent := string(content)
+	if newContent == lastConfigContent {
+		return false, nil
+	}
+
+	newConfig, err := parseConfig(newContent)
+	if err != nil {
+		return false, err
 	}
+	lastConfigContent = newContent
 
 	configMutex.Lock()
 	currentConfig = newConfig
 	configMutex.Unlock()
 
-	return true
+	return true, nil
+}
+
+// decorate adds per-line annotations, such as the source tag, to a formatted line.
+func decorate(log LogEntry, formatted styledLine) styledLine {
+	if log.Source != "" {
+		formatted = formatted.prepend(plainText("[" + log.Source + "] "))
+	}
+	if log.New {
+		formatted = formatted.prepend(styledText(baselineMarker, Green).append(plainText(" ")))
+	}
+	if log.Group != "" {
+		formatted = formatted.prepend(styledText(windowMarker, log.Group).append(plainText(" ")))
+	}
+	if colorByField != "" || colorByPattern != nil {
+		formatted = formatted.prepend(colorByPrefix(log.Text))
+	}
+	var badges []styledLine
+	if markNew && log.FirstSeen {
+		badges = append(badges, styledText(newBadge, Dim))
+	}
+	if log.Measured {
+		badges = append(badges, measureBadge(log.Elapsed))
+	}
+	if enricher != nil {
+		badges = append(badges, enricher.annotations(log.Text))
+	}
+	if showRule {
+		configMutex.RLock()
+		badges = append(badges, ruleTags(currentConfig, log.Text))
+		configMutex.RUnlock()
+	}
+	for _, badge := range badges {
+		if badge.text != "" {
+			formatted = formatted.append(plainText(" ")).append(badge)
+		}
+	}
+	formatted.source, formatted.time = log.Source, log.Time
+	return formatted
+}
+
+// renderLines formats the stored logs that pass the filters, along with any
+// separators between them. The caller must hold logsMutex.
+func renderLines() []displayLine {
+	configMutex.RLock()
+	cfg := currentConfig
+	configMutex.RUnlock()
+
+	var lines []displayLine
+	var traced []renderedEntry
+	var prev *LogEntry
+	var prevMatches string
+	var muted mutedRun
+	var templates templateGroups
+	viewMutex.Lock()
+	term := searchTerm
+	viewMutex.Unlock()
+	entries := storedLogs
+	if sorted {
+		entries = sortedEntries(storedLogs)
+	}
+	for i, log := range entries {
+		if searchRejects(term, log.Text) || (onlyNew && !log.New) {
+			continue
+		}
+		if formattedLog, shown := filterAndHighlight(log.Text); shown {
+			if log.Muted != "" {
+				lines = muted.add(lines, cfg, log)
+				continue
+			}
+			lines = muted.flush(lines)
+			if templateGroup {
+				templates.add(log)
+				continue
+			}
+			if traceView {
+				traced = append(traced, renderedEntry{log, formattedLog})
+				continue
+			}
+			if onChange {
+				matches := matchSet(cfg, log.Text)
+				if prev != nil && matches == prevMatches {
+					continue
+				}
+				prevMatches = matches
+			}
+			if prev != nil {
+				if separator := gapSeparator(*prev, log); separator.text != "" {
+					lines = append(lines, displayLine{styledLine: separator})
+				}
+			}
+			prev = &entries[i]
+			if onlyMatching {
+				lines = append(lines, matchRows(cfg, log)...)
+				continue
+			}
+			lines = append(lines, displayLine{styledLine: decorate(log, formattedLog), id: log.ID, isLog: true})
+		}
+	}
+	lines = muted.flush(lines)
+	if templateGroup {
+		lines = append(lines, templates.rows(cfg)...)
+	}
+	if traceView {
+		lines = traceTree(traced)
+	}
+	return collapseMatches(lines)
 }
 
 // reprintLogs clears the terminal and reprints all logs with the current configuration.
 func reprintLogs() {
+	renderMutex.Lock()
+	defer renderMutex.Unlock()
 	logsMutex.RLock()
 	defer logsMutex.RUnlock()
 
-	fmt.Print(ClearScreen)
-	for _, log := range storedLogs {
-		if formattedLog := filterAndHighlight(log); formattedLog != "" {
-			fmt.Println(formattedLog)
+	if displaySuspended {
+		return
+	}
+	if !activeFormatter.Live() && !finalFrame {
+		return
+	}
+	if isIdle() {
+		fmt.Fprint(out, clearSequence)
+		writeDashboard(out)
+		out.endFrame()
+		return
+	}
+	var lines []displayLine
+	if topActive() {
+		lines = topLines()
+	} else {
+		lines = append(renderLines(), partialRows()...)
+	}
+	if interactive {
+		drawView(out, lines)
+	} else {
+		fmt.Fprint(out, clearSequence)
+		activeFormatter.Begin(out)
+		writeLines(out, activeFormatter, lines)
+		writeAlertFooter(out)
+		if message := configErrorMessage(); message != "" && isTerminal(os.Stdout) {
+			activeFormatter.Line(out, styledText(message, Red))
 		}
+		activeFormatter.End(out)
 	}
+	out.endFrame()
+}
+
+// writeLines writes rendered lines to w in a format, one per line.
+func writeLines(w io.Writer, f Formatter, lines []displayLine) {
+	for _, line := range lines {
+		f.Line(w, line.styledLine)
+	}
+}
+
+// appendRawLog records a line as it was read, with its line ending, and
+// stores it.
+func appendRawLog(source, raw string) {
+	recordLine(source, raw)
+	appendLog(source, trimLineEnding(raw))
 }
 
-// appendLog stores a log line and triggers reprint of all logs.
-func appendLog(line string) {
+// appendLog stores a log line and triggers reprint of all logs. Inputs that
+// read lines with their endings go through appendRawLog instead, so --record
+// keeps the endings.
+func appendLog(source, line string) {
+	if handleCR {
+		line = collapseCarriageReturns(line)
+	}
+	if lineTransform != nil {
+		var keep bool
+		if line, keep = lineTransform(source, line); !keep {
+			return
+		}
+	}
+	entry := LogEntry{Text: line, Source: source, Arrived: time.Now()}
+	entry.Time, _ = parseTimestamp(line)
+	entry.New = !inBaseline(line)
+
 	logsMutex.Lock()
-	storedLogs = append(storedLogs, line)
+	nextLogID++
+	entry.ID = nextLogID
+	lastLineAt = entry.Arrived
+	if markNew {
+		configMutex.RLock()
+		markFirstSeen(currentConfig, &entry)
+		configMutex.RUnlock()
+	}
+	configMutex.RLock()
+	markMuted(currentConfig, &entry)
+	if metricsAddr != "" || idleDashboard > 0 {
+		countLine(currentConfig, line)
+	}
+	configMutex.RUnlock()
+	if measure != nil {
+		markMeasured(&entry)
+	}
+	if timeMerge && !entry.Time.IsZero() {
+		storedLogs = insertByTime(storedLogs, entry)
+	} else {
+		storedLogs = append(storedLogs, entry)
+	}
+	if explain {
+		configMutex.RLock()
+		explainEntry(currentConfig, entry)
+		configMutex.RUnlock()
+	}
+	if topActive() {
+		configMutex.RLock()
+		countTop(currentConfig, line)
+		configMutex.RUnlock()
+	}
+	if alertFooter > 0 || eventFile != nil || bell {
+		configMutex.RLock()
+		reactToAlerts(currentConfig, entry)
+		configMutex.RUnlock()
+	}
+	if windowMatch > 1 {
+		configMutex.RLock()
+		markWindowMatches(currentConfig)
+		configMutex.RUnlock()
+	}
+	evictExpired()
 	logsMutex.Unlock()
 
-	reprintLogs()
+	requestReprint()
 }
 
 // readLogs continuously reads logs from the input and stores them.
-func readLogs(scanner *bufio.Scanner) {
+func readLogs(scanner *bufio.Scanner, source string) {
 	for scanner.Scan() {
-		line := scanner.Text()
-		appendLog(line)
+		appendRawLog(source, scanner.Text())
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -161,44 +596,604 @@ func readLogs(scanner *bufio.Scanner) {
 }
 
 // pollConfig periodically checks for changes in the configuration file.
-func pollConfig(configPath string, interval time.Duration) {
+func pollConfig(interval time.Duration) {
 	for {
-		if loadConfig(configPath) {
-			fmt.Println("Config file reloaded.")
-			reprintLogs()
-		}
+		reloadConfig(currentConfigFile())
 		time.Sleep(interval)
 	}
 }
 
+// reloadConfig loads the config file if it changed, refreshes the state that
+// depends on it, and reprints.
+func reloadConfig(configPath string) bool {
+	changed, err := loadConfig(configPath)
+	if reportConfigError(err) {
+		reprintLogs()
+	}
+	if !changed {
+		return false
+	}
+	configReloadsTotal.inc()
+	if !interactive {
+		fmt.Fprintln(os.Stderr, "Config file reloaded.")
+	}
+	configMutex.RLock()
+	cfg := currentConfig
+	configMutex.RUnlock()
+	checkMinLevel(cfg)
+	if markNew {
+		refreshFirstSeen(cfg)
+	}
+	refreshMutes(cfg)
+	refreshAlerts(cfg)
+	reprintLogs()
+	return true
+}
+
+// optionalInputs are input sources compiled in with build tags. After flags
+// are parsed, each returns a function that reads its source, or nil if the
+// source wasn't requested.
+var optionalInputs []func() func()
+
+// optionalSetups are options compiled in with build tags that need the parsed
+// flags, run before any input is read.
+var optionalSetups []func()
+
+// lineTransform rewrites each input line before it is stored, or returns
+// false to drop it. It is set by --transform-script in binaries built with
+// -tags expr.
+var lineTransform func(source, line string) (string, bool)
+
+// stringList is a flag.Value that collects every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// flagSet reports whether a flag was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func main() {
 	// Command-line flags for config and input files.
+	var inputPaths stringList
 	configPath := flag.String("config", "config.txt", "Path to the configuration file")
-	inputPath := flag.String("input", "", "Path to the input log file (optional)")
+	flag.Var(&inputPaths, "input", "Path to an input log file; repeat to merge several (optional)")
+	forceStdin := flag.Bool("stdin", false, "Read from stdin even when it is an interactive terminal")
 	pollInterval := flag.Duration("interval", 2*time.Second, "Polling interval for config file changes")
+	filterCommand := flag.String("filter-cmd", "", "Command that decides keep/drop for each line over stdin/stdout (optional)")
+	filterFail := flag.String("filter-cmd-fail", "open", "Behavior when the filter command fails: open (keep lines) or closed (drop lines)")
+	flag.BoolVar(&smartUnits, "smart-units", false, "Color sizes and durations (e.g. 512MB, 204ms) by magnitude")
+	flag.BoolVar(&resumeEnabled, "resume", false, "Continue the input file from where the previous run stopped")
+	flag.StringVar(&resumeStatePath, "resume-state", defaultResumeStatePath(), "Path to the file that stores --resume offsets")
+	flag.BoolVar(&timeMerge, "time-merge", false, "Order lines by their parsed timestamps instead of arrival")
+	flag.IntVar(&reorderWindow, "reorder-window", 1000, "How many recent lines --time-merge may reorder across")
+	flag.StringVar(&minLevel, "min-level", "", "Hide lines whose log level is below this one (e.g. warn)")
+	flag.BoolVar(&handleCR, "handle-cr", false, "Treat carriage returns as overwrites so progress bars keep only their last frame")
+	autoColorTokens := flag.String("auto-color-tokens", "", `Give each distinct match of this regex its own color, e.g. "tid=\w+"`)
+	flag.DurationVar(&gapThreshold, "gap-separator", 0, "Insert a separator between lines more than this far apart (e.g. 5s)")
+	flag.StringVar(&gapFormat, "gap-format", "──── {gap} gap ────", "Separator text for --gap-separator; {gap} is replaced by the gap length")
+	var fieldFilterFlags stringList
+	flag.Var(&fieldFilterFlags, "field-filter", `Filter JSON lines by a nested value, e.g. "$.request.headers.host=example.com"; repeat to require several`)
+	query := flag.String("query", "", `Filter structured lines with a query, e.g. 'level = "error" and status >= 500'`)
+	flag.BoolVar(&interactive, "interactive", false, "Browse the logs with the keyboard instead of printing everything")
+	flushPolicy := flag.String("flush", defaultFlushPolicy(), "Output flushing: line, block, or a number of lines between flushes")
+	flag.BoolVar(&flushStats, "flush-stats", false, "Report output flush statistics on exit")
+	flag.BoolVar(&focusMode, "focus", false, "Show lines that don't match the filters dimmed instead of hiding them")
+	flag.BoolVar(&markNew, "mark-new", false, "Badge the first line each highlight keyword appears on with (new)")
+	format := flag.String("format", "ansi", "Output format: ansi, plain (no escapes), or html (a page of the final view)")
+	clearSeq := flag.String("clear-seq", "ansi", "Screen clear before each reprint: ansi, scrollback, cursor-home-only, none, or a literal like \\033[2J")
+	flag.BoolVar(&onChange, "on-change", false, "Only show lines whose matched highlight keywords differ from the previous shown line's")
+	flag.IntVar(&maxDisplay, "max-display", 0, "Show only the first and last N matching lines, summarizing the rest")
+	flag.BoolVar(&traceView, "trace-view", false, "Group structured lines by trace and indent them by span depth")
+	flag.StringVar(&traceFields, "trace-fields", traceFields, "Field names for --trace-view: trace,span,parent")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address, e.g. :9100")
+	flag.DurationVar(&searchDebounce, "search-debounce", searchDebounce, "Delay before re-rendering while typing a search")
+	flag.StringVar(&baselinePath, "baseline", "", "Mark lines that don't appear in this earlier log file")
+	flag.BoolVar(&onlyNew, "only-new", false, "With --baseline, show only the lines not in the baseline")
+	flag.StringVar(&streamURL, "url", "", "Stream lines from an HTTP endpoint, reconnecting when it disconnects")
+	flag.Var(&streamHeaders, "header", "HTTP header for --url as \"Name: value\"; repeat for several")
+	flag.StringVar(&trimPrefix, "trim-prefix", "", "Hide this prefix at the start of displayed lines")
+	trimPrefixRegex := flag.String("trim-prefix-regex", "", "Hide the match of this regex at the start of displayed lines")
+	flag.BoolVar(&respectInputColor, "respect-input-color", false, "Don't highlight lines that already contain colors; only filter them")
+	flag.BoolVar(&templateGroup, "template-group", false, "Show lines that differ only in numbers once, as a template with a count")
+	templateRegex := flag.String("template-regex", "", "What --template-group masks as <N> (default every run of digits)")
+	flag.Float64Var(&maxCPU, "max-cpu", 0, "Keep rendering new lines under this percentage of one core by drawing less often (e.g. 25)")
+	flag.BoolVar(&showRule, "show-rule", false, "Show the highlight rules that matched each line after it, e.g. [red:error]")
+	flag.BoolVar(&matchTrimmed, "match-trimmed", false, "Filter and highlight lines with the prefix already trimmed")
+	cols := flag.String("cols", "", "Show only these 1-based columns, in this order, e.g. 3,1,5")
+	flag.StringVar(&delimiter, "delim", "", "Column delimiter for --cols (default runs of whitespace)")
+	flag.BoolVar(&matchProjected, "match-projected", false, "Filter and highlight only the columns selected by --cols")
+	flag.DurationVar(&idleDashboard, "idle-dashboard", 0, "Show a summary after the input has been quiet this long (e.g. 30s)")
+	flag.BoolVar(&unicodeFold, "unicode-fold", false, "Ignore case with full Unicode case folding (slower) when filtering and highlighting")
+	flag.DurationVar(&retainFor, "retain", 0, "Drop buffered lines that arrived longer ago than this (e.g. 10m)")
+	flag.BoolVar(&retainByTimestamp, "retain-by-timestamp", false, "With --retain, age lines by their parsed timestamps instead of their arrival, which evicts old files as they are read")
+	enrichCommand := flag.String("enrich-cmd", "", "Command that annotates tokens over stdin/stdout, one per line (optional)")
+	enrichRegex := flag.String("enrich-regex", enrichPattern.String(), "Tokens for --enrich-cmd: the first capture group, or the whole match")
+	flag.BoolVar(&matchBrackets, "match-brackets", false, "Color matched bracket and quote pairs by depth, and unbalanced ones red")
+	flag.BoolVar(&onlyMatching, "only-matching", false, "Show only the parts of lines that highlight rules match, one per line")
+	flag.BoolVar(&noBlink, "no-blink", false, "Ignore the blink attribute in highlight rules")
+	flag.BoolVar(&autoContrast, "auto-contrast", false, "Skip automatically assigned colors that are hard to read on the background")
+	flag.BoolVar(&autoContrastRules, "auto-contrast-rules", false, "With --auto-contrast, also replace hard-to-read colors chosen in the config")
+	background := flag.String("background", "auto", "Terminal background for --auto-contrast: dark, light, or auto (ask the terminal, then COLORFGBG)")
+	forceBanner := flag.Bool("banner", false, "Print a summary of the effective settings at startup, even when not on a terminal")
+	noBanner := flag.Bool("no-banner", false, "Don't print the startup summary on a terminal")
+	flag.IntVar(&windowMatch, "window-match", 0, "Experimental: also match rules across N consecutive lines, marking them (costly)")
+	flag.StringVar(&sortField, "sort", "", "Once the input ends, show lines sorted by this JSON or key=value field")
+	sortRegex := flag.String("sort-regex", "", "Once the input ends, show lines sorted by this regex's first capture group")
+	flag.BoolVar(&sortDesc, "sort-desc", false, "Sort in descending order")
+	flag.BoolVar(&explain, "explain", false, "Print to stderr why each line is shown or hidden")
+	flag.IntVar(&alertFooter, "alert-footer", 0, "Keep the last N lines matching alert rules (word = color, alert) in a footer")
+	flag.StringVar(&topField, "top", "", "Show the most frequent values of this JSON or key=value field instead of the lines")
+	measureSpec := flag.String("measure", "", "Annotate lines matching end with the time since the last line matching start, as \"start:<regex>,end:<regex>[,warn:200ms][,crit:1s]\"")
+	topRegex := flag.String("top-regex", "", "Show the most frequent matches of this regex's first capture group instead of the lines")
+	flag.IntVar(&topN, "top-n", 10, "How many values --top and --top-regex show")
+	flag.BoolVar(&showPartial, "show-partial", false, "With --follow-all or --latest, show a file's unfinished last line dimmed until its newline arrives")
+	flag.BoolVar(&bell, "bell", false, "Ring the terminal bell when an alert rule (word = color, alert) matches")
+	eventFD := flag.Int("event-fd", 0, "Write a JSON event to this file descriptor for each alert rule match, e.g. 3")
+	encodingName := flag.String("encoding", "utf-8", "Encoding of the input, e.g. latin1, utf16, windows-1252, shift_jis")
+	flag.StringVar(&recordPath, "record", "", "Copy every raw input line verbatim to this file, and its timing to the file plus .timing, for --replay")
+	flag.StringVar(&replayPath, "replay", "", "Read input from a --record file, with its original timing and order")
+	flag.IntVar(&regexCacheSize, "regex-cache", 0, "Keep at most N compiled highlight regexes, compiling rules on first use (0 keeps all)")
+	preset := flag.String("preset", "", "Use a saved preset (see --list-presets) as the config file")
+	listPresetsFlag := flag.Bool("list-presets", false, "List the saved presets and exit")
+	dumpConfig := flag.Bool("dump-config", false, "Print the effective config in config file format and exit")
+	followPattern := flag.String("follow-all", "", "Follow every file matching this glob, including files created later")
+	latestPattern := flag.String("latest", "", "Follow the newest file matching this glob, switching when a newer one appears")
+	flag.StringVar(&colorByField, "color-by", "", "Color each line's prefix by the value of this JSON or key=value field")
+	colorByRegex := flag.String("color-by-regex", "", "Color each line's prefix by this regex's first capture group")
 
 	flag.Parse()
 
+	if *listPresetsFlag {
+		names, err := listPresets()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error listing presets:", err)
+			os.Exit(1)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	// Run cleanup on interrupt as well as at the end of input.
+	go handleSignals()
+
+	seq, err := parseClearSequence(*clearSeq)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in --clear-seq:", err)
+		os.Exit(2)
+	}
+	clearSequence = seq
+
+	if f, ok := formatters[*format]; ok {
+		activeFormatter = f
+	} else {
+		fmt.Fprintln(os.Stderr, "Error: --format must be ansi, plain, or html")
+		os.Exit(2)
+	}
+	if *format != "ansi" {
+		if interactive || idleDashboard > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --interactive and --idle-dashboard need --format ansi")
+			os.Exit(2)
+		}
+		// A frame written once, at exit, has nothing to clear.
+		if !activeFormatter.Live() && !flagSet("clear-seq") {
+			clearSequence = ""
+		}
+	}
+
+	if out = newFlushWriter(*flushPolicy); out == nil {
+		fmt.Fprintln(os.Stderr, "Error: --flush must be line, block, or a positive number")
+		os.Exit(2)
+	}
+	atExit(out.close)
+	if !activeFormatter.Live() {
+		atExit(writeFinalFrame)
+	}
+
+	if *eventFD != 0 {
+		if err := openEventFD(*eventFD); err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --event-fd:", err)
+			os.Exit(2)
+		}
+	}
+
+	enc, err := parseEncoding(*encodingName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in --encoding:", err)
+		os.Exit(2)
+	}
+	inputEncoding = enc
+	if inputEncoding != nil && resumeEnabled {
+		fmt.Fprintln(os.Stderr, "Error: --resume works on byte offsets and needs UTF-8 input")
+		os.Exit(2)
+	}
+
+	if recordPath != "" {
+		if sameFile(recordPath, replayPath) {
+			fmt.Fprintln(os.Stderr, "Error in --record: can't record over the --replay file")
+			os.Exit(2)
+		}
+		if err := startRecording(recordPath); err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --record:", err)
+			os.Exit(2)
+		}
+	}
+
+	dark, err := parseBackground(*background)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in --background:", err)
+		os.Exit(2)
+	}
+	darkBackground = dark
+	autoContrastRules = autoContrastRules && autoContrast
+	if autoContrast {
+		palette = contrastPalette()
+	}
+
+	if *colorByRegex != "" {
+		re, err := regexp.Compile(*colorByRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --color-by-regex:", err)
+			os.Exit(2)
+		}
+		colorByPattern = re
+	}
+
+	if *autoColorTokens != "" {
+		re, err := regexp.Compile(*autoColorTokens)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --auto-color-tokens:", err)
+			os.Exit(2)
+		}
+		autoColorPattern = re
+	}
+
+	if *topRegex != "" {
+		re, err := regexp.Compile(*topRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --top-regex:", err)
+			os.Exit(2)
+		}
+		topPattern = re
+	}
+	if topN < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --top-n must be at least 1")
+		os.Exit(2)
+	}
+
+	if *measureSpec != "" {
+		m, err := parseMeasure(*measureSpec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --measure:", err)
+			os.Exit(2)
+		}
+		measure = m
+	}
+
+	if *templateRegex != "" {
+		re, err := regexp.Compile(*templateRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --template-regex:", err)
+			os.Exit(2)
+		}
+		templatePattern = re
+	}
+
+	if *sortRegex != "" {
+		re, err := regexp.Compile(*sortRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --sort-regex:", err)
+			os.Exit(2)
+		}
+		sortPattern = re
+	}
+	if (sortField != "" || sortPattern != nil) && (*followPattern != "" || *latestPattern != "" || streamURL != "") {
+		fmt.Fprintln(os.Stderr, "Error: --sort needs inputs that end; it can't be combined with --follow-all, --latest, or --url")
+		os.Exit(2)
+	}
+
+	if *trimPrefixRegex != "" {
+		re, err := regexp.Compile(*trimPrefixRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --trim-prefix-regex:", err)
+			os.Exit(2)
+		}
+		trimPrefixPattern = re
+	}
+
+	if *cols != "" {
+		selected, err := parseColumns(*cols)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --cols:", err)
+			os.Exit(2)
+		}
+		columns = selected
+	}
+
+	if *query != "" {
+		node, err := parseQuery(*query)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --query:", err)
+			os.Exit(2)
+		}
+		activeQuery = node
+	}
+
+	for _, src := range fieldFilterFlags {
+		filter, err := parseFieldFilter(src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --field-filter:", err)
+			os.Exit(2)
+		}
+		fieldFilters = append(fieldFilters, filter)
+	}
+
+	// Start the external filter, if one was requested.
+	if strings.TrimSpace(*filterCommand) != "" {
+		if *filterFail != "open" && *filterFail != "closed" {
+			fmt.Fprintln(os.Stderr, "Error: --filter-cmd-fail must be open or closed")
+			os.Exit(2)
+		}
+		externalFilter = newFilterCmd(*filterCommand, *filterFail == "open")
+		atExit(externalFilter.stop)
+	}
+
+	if strings.TrimSpace(*enrichCommand) != "" {
+		re, err := regexp.Compile(*enrichRegex)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --enrich-regex:", err)
+			os.Exit(2)
+		}
+		enrichPattern = re
+		enricher = newEnrichCmd(*enrichCommand)
+		atExit(enricher.stop)
+	}
+
+	if baselinePath != "" {
+		if err := loadBaseline(baselinePath); err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --baseline:", err)
+			os.Exit(2)
+		}
+	} else if onlyNew {
+		fmt.Fprintln(os.Stderr, "Error: --only-new needs --baseline")
+		os.Exit(2)
+	}
+
 	// Load the initial configuration.
-	loadConfig(*configPath)
+	configFile = *configPath
+	if *preset != "" {
+		if flagSet("config") {
+			fmt.Fprintln(os.Stderr, "Error: --preset and --config can't be combined")
+			os.Exit(2)
+		}
+		path, err := presetPath(*preset)
+		if err == nil {
+			_, err = os.Stat(path)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --preset:", err)
+			os.Exit(2)
+		}
+		configFile = path
+	}
+	if _, err := loadConfig(configFile); err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading config file:", err)
+		// Polling reads the file again; report the error then only if it changes.
+		configErrorMutex.Lock()
+		configError = err
+		configErrorMutex.Unlock()
+	}
+	checkMinLevel(currentConfig)
+	seenKeywordSet = keywordSet(currentConfig)
+	muteRuleSet = muteRules(currentConfig)
+
+	if *dumpConfig {
+		fmt.Print(formatConfig(currentConfig))
+		return
+	}
+
+	if interactive {
+		if err := startInteractive(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error starting interactive mode:", err)
+			os.Exit(1)
+		}
+		reprintLogs()
+	}
+
+	if metricsAddr != "" {
+		if err := serveMetrics(metricsAddr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --metrics-addr:", err)
+			os.Exit(2)
+		}
+	}
+
+	if idleDashboard > 0 {
+		go watchIdle()
+	}
+	if maxCPU < 0 || maxCPU > 100 {
+		fmt.Fprintln(os.Stderr, "Error: --max-cpu must be between 0 and 100")
+		os.Exit(2)
+	}
+	if maxCPU > 0 && maxCPU < 100 {
+		go renderThrottled()
+	} else {
+		maxCPU = 0
+	}
+	if retainFor > 0 {
+		go watchRetention()
+	}
+
+	for _, setup := range optionalSetups {
+		setup()
+	}
 
 	// Start polling the config file for changes.
-	go pollConfig(*configPath, *pollInterval)
+	go pollConfig(*pollInterval)
 
-	// Use standard input or read from a file.
-	var scanner *bufio.Scanner
-	if *inputPath != "" {
-		file, err := os.Open(*inputPath)
+	// Use standard input or read from the given files. When several sources are
+	// merged, lines are tagged with the file they came from.
+	type input struct {
+		scanner *bufio.Scanner
+		source  string
+	}
+	var inputs []input
+	for _, inputPath := range inputPaths {
+		file, err := os.Open(inputPath)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "Error opening input file:", err)
 			os.Exit(1)
 		}
 		defer file.Close()
-		scanner = bufio.NewScanner(file)
-	} else {
-		scanner = bufio.NewScanner(os.Stdin)
+		scanner := bufio.NewScanner(decodeInput(file))
+		scanner.Split(scanRawLines)
+		if resumeEnabled {
+			resumeFrom(file, scanner)
+		}
+		source := ""
+		if len(inputPaths) > 1 || *followPattern != "" || *latestPattern != "" {
+			source = filepath.Base(inputPath)
+		}
+		inputs = append(inputs, input{scanner, source})
+	}
+	var extraReaders []func()
+	for _, optionalInput := range optionalInputs {
+		if read := optionalInput(); read != nil {
+			extraReaders = append(extraReaders, read)
+		}
+	}
+	if *latestPattern != "" {
+		if _, err := filepath.Match(*latestPattern, ""); err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --latest:", err)
+			os.Exit(2)
+		}
+		extraReaders = append(extraReaders, func() { followLatest(*latestPattern) })
+	}
+	if streamURL != "" {
+		header, err := parseHeaders(streamHeaders)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --header:", err)
+			os.Exit(2)
+		}
+		extraReaders = append(extraReaders, func() { followURL(streamURL, header) })
+	}
+	if replayPath != "" {
+		file, err := os.Open(replayPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --replay:", err)
+			os.Exit(2)
+		}
+		extraReaders = append(extraReaders, func() { replayRecording(file) })
+	}
+	if len(inputs) == 0 && *followPattern == "" && len(extraReaders) == 0 {
+		// Reading a terminal nobody is typing into looks like a hang.
+		if isTerminal(os.Stdin) && !*forceStdin {
+			fmt.Fprintln(os.Stderr, "No input: pipe logs into loggo or use --input (--stdin reads the terminal anyway).")
+			os.Exit(2)
+		}
+		if resumeEnabled {
+			fmt.Fprintln(os.Stderr, "Warning: --resume requires --input, reading stdin from the start")
+		}
+		scanner := bufio.NewScanner(decodeInput(os.Stdin))
+		scanner.Split(scanRawLines)
+		inputs = append(inputs, input{scanner, ""})
+	}
+
+	if *format == "ansi" && (*forceBanner || (isTerminal(os.Stdout) && !*noBanner && !interactive)) {
+		info := bannerInfo{sources: append([]string(nil), inputPaths...)}
+		if len(inputPaths) == 0 && len(inputs) > 0 {
+			info.sources = append(info.sources, "stdin")
+		}
+		if *followPattern != "" {
+			info.sources = append(info.sources, "follow-all "+*followPattern)
+		}
+		if *latestPattern != "" {
+			info.sources = append(info.sources, "latest "+*latestPattern)
+		}
+		if streamURL != "" {
+			info.sources = append(info.sources, "url "+streamURL)
+		}
+		modes := []struct {
+			name string
+			on   bool
+		}{
+			{"time-merge", timeMerge}, {"focus", focusMode}, {"trace-view", traceView},
+			{"only-matching", onlyMatching}, {"only-new", onlyNew}, {"on-change", onChange},
+		}
+		for _, mode := range modes {
+			if mode.on {
+				info.modes = append(info.modes, mode.name)
+			}
+		}
+		writeBanner(out, info)
 	}
 
-	// Continuously read logs.
-	readLogs(scanner)
+	// Continuously read logs from every input.
+	var readers sync.WaitGroup
+	for _, in := range inputs {
+		readers.Add(1)
+		go func(in input) {
+			defer readers.Done()
+			readLogs(in.scanner, in.source)
+		}(in)
+	}
+	for _, read := range extraReaders {
+		readers.Add(1)
+		go func(read func()) {
+			defer readers.Done()
+			read()
+		}(read)
+	}
+	if *followPattern != "" {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			followAll(*followPattern)
+		}()
+	}
+	inputEnded := make(chan struct{})
+	go func() {
+		readers.Wait()
+		close(inputEnded)
+	}()
+	select {
+	case <-inputEnded:
+	case <-quit:
+		// Quitting while input still streams skips the end-of-input views.
+		runExitHooks()
+		return
+	}
+	if maxCPU > 0 {
+		// Draw the lines still waiting for a throttled render.
+		reprintLogs()
+	}
+
+	// Show the sorted view now that the input is complete.
+	if sortField != "" || sortPattern != nil {
+		logsMutex.Lock()
+		sorted = true
+		logsMutex.Unlock()
+		reprintLogs()
+	}
+
+	// Keep browsing after the input ends until the user quits.
+	if interactive {
+		<-quit
+	}
+	runExitHooks()
 }
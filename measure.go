@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// measureSpec times the gap between a line matching start and the next line
+// matching end (--measure), and annotates the end line with it.
+type measureSpec struct {
+	start, end *regexp.Regexp
+	warn, crit time.Duration // The elapsed time is yellow from warn and red from crit
+}
+
+// measure is the --measure spec, nil when no pairs are timed.
+var measure *measureSpec
+
+// Measuring state, guarded by logsMutex: the pending start line's parsed and
+// arrival times.
+var measureStart LogEntry
+var measureOpen bool
+
+// measureKey splits a --measure spec before each of its keys, so the patterns
+// may contain commas.
+var measureKey = regexp.MustCompile(`(^|,)(start|end|warn|crit):`)
+
+// parseMeasure parses a spec like "start:begin job,end:job done,warn:200ms,crit:1s".
+// warn and crit default to the thresholds --smart-units colors durations by.
+func parseMeasure(spec string) (*measureSpec, error) {
+	m := &measureSpec{warn: 100 * time.Millisecond, crit: time.Second}
+	keys := measureKey.FindAllStringSubmatchIndex(spec, -1)
+	if len(keys) == 0 || keys[0][0] != 0 {
+		return nil, fmt.Errorf("expected start:<regex>,end:<regex>")
+	}
+	seen := make(map[string]bool)
+	for i, k := range keys {
+		key := spec[k[4]:k[5]]
+		end := len(spec)
+		if i+1 < len(keys) {
+			end = keys[i+1][0]
+		}
+		value := spec[k[1]:end]
+		if seen[key] {
+			return nil, fmt.Errorf("%s given twice", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "start", "end":
+			if value == "" {
+				return nil, fmt.Errorf("empty %s pattern", key)
+			}
+			var re *regexp.Regexp
+			if re, err = regexp.Compile(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			if key == "start" {
+				m.start = re
+			} else {
+				m.end = re
+			}
+		case "warn":
+			m.warn, err = time.ParseDuration(value)
+		case "crit":
+			m.crit, err = time.ParseDuration(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if m.start == nil || m.end == nil {
+		return nil, fmt.Errorf("both start: and end: are required")
+	}
+	if m.crit < m.warn {
+		return nil, fmt.Errorf("crit must not be below warn")
+	}
+	return m, nil
+}
+
+// markMeasured records a start line, or stores the time since the pending
+// start on an end line. A line matching both ends one pair and starts the
+// next. Times come from the lines' timestamps when both have one, and from
+// arrival otherwise. The caller must hold logsMutex for writing.
+func markMeasured(entry *LogEntry) {
+	text := stripANSI(entry.Text)
+	if measureOpen && measure.end.MatchString(text) {
+		elapsed := entry.Arrived.Sub(measureStart.Arrived)
+		if !entry.Time.IsZero() && !measureStart.Time.IsZero() {
+			elapsed = entry.Time.Sub(measureStart.Time)
+		}
+		entry.Elapsed = max(elapsed, 0)
+		entry.Measured = true
+		measureOpen = false
+	}
+	if measure.start.MatchString(text) {
+		measureStart = LogEntry{Time: entry.Time, Arrived: entry.Arrived}
+		measureOpen = true
+	}
+}
+
+// measureBadge renders the elapsed time of a measured line, colored by the
+// thresholds.
+func measureBadge(elapsed time.Duration) styledLine {
+	color := Green
+	switch {
+	case elapsed >= measure.crit:
+		color = Red
+	case elapsed >= measure.warn:
+		color = Yellow
+	}
+	return styledText("(+"+formatElapsed(elapsed)+")", color)
+}
+
+// formatElapsed shortens a duration to three significant digits or so, as
+// "1.23s" rather than "1.234567891s".
+func formatElapsed(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		d = d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		d = d.Round(10 * time.Microsecond)
+	}
+	return strings.TrimSuffix(d.String(), ".00")
+}
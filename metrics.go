@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// metricsAddr is the address to serve Prometheus metrics on (--metrics-addr).
+var metricsAddr string
+
+// maxKeywordLabels bounds the keyword label values of loggo_matches_total.
+// Matches of further keywords are counted under "other".
+const maxKeywordLabels = 50
+
+// metric is a metric in the Prometheus text exposition format.
+type metric interface {
+	writeTo(w io.Writer)
+}
+
+// counter is a metric that only goes up.
+type counter struct {
+	name, help string
+	value      atomic.Uint64
+}
+
+func (c *counter) inc() { c.value.Add(1) }
+
+func (c *counter) writeTo(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value.Load())
+}
+
+// labeledCounter is a counter with one label, holding at most limit values.
+type labeledCounter struct {
+	name, help, label string
+	limit             int
+
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+func (c *labeledCounter) inc(value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.values[value]; !ok && len(c.values) >= c.limit {
+		value = "other"
+	}
+	c.values[value]++
+}
+
+// snapshot returns a copy of the counts by label value.
+func (c *labeledCounter) snapshot() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := make(map[string]uint64, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+	return values
+}
+
+func (c *labeledCounter) writeTo(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", c.name, c.label, labelEscaper.Replace(k), c.values[k])
+	}
+}
+
+// labelEscaper escapes label values for the exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// gauge is a metric whose value is read when scraped.
+type gauge struct {
+	name, help string
+	value      func() float64
+}
+
+func (g *gauge) writeTo(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.value())
+}
+
+// The registered metrics, in exposition order.
+var (
+	linesTotal = &counter{name: "loggo_lines_total", help: "Lines read from all inputs."}
+
+	matchesTotal = &labeledCounter{
+		name: "loggo_matches_total", help: "Lines matching each highlight keyword.",
+		label: "keyword", limit: maxKeywordLabels, values: make(map[string]uint64),
+	}
+
+	configReloadsTotal = &counter{name: "loggo_config_reloads_total", help: "Config file reloads applied."}
+
+	bufferLines = &gauge{name: "loggo_buffer_lines", help: "Lines held in the buffer.", value: func() float64 {
+		logsMutex.RLock()
+		defer logsMutex.RUnlock()
+		return float64(len(storedLogs))
+	}}
+
+	metricsRegistry = []metric{linesTotal, matchesTotal, configReloadsTotal, bufferLines}
+)
+
+// countLine updates the line and match metrics for a new line.
+func countLine(cfg Config, line string) {
+	linesTotal.inc()
+	for _, word := range matchedRules(cfg, line) {
+		matchesTotal.inc(word)
+	}
+}
+
+// serveMetrics starts serving the registered metrics on /metrics.
+func serveMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		for _, m := range metricsRegistry {
+			m.writeTo(w)
+		}
+	})
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			fmt.Fprintln(os.Stderr, "Error serving metrics:", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Mute state, guarded by logsMutex: the arrival times of the latest matches of
+// each mute_after rule, at most MuteAfter of them.
+var muteMatches = make(map[string][]time.Time)
+var muteRuleSet string // The mute_after rules muteMatches was built for
+
+// muteRules identifies a config's mute_after rules, to detect when they change.
+func muteRules(cfg Config) string {
+	var rules []string
+	for _, rule := range cfg.Highlights {
+		if rule.MuteAfter > 0 {
+			rules = append(rules, fmt.Sprintf("%s/%d/%s", rule.Word, rule.MuteAfter, rule.Window))
+		}
+	}
+	return strings.Join(rules, "\x00")
+}
+
+// markMuted flags the entry if it matches a mute_after rule that has already
+// matched MuteAfter times within its window. The caller must hold logsMutex
+// for writing.
+func markMuted(cfg Config, entry *LogEntry) {
+	entry.Muted = ""
+	for _, rule := range cfg.Highlights {
+		if rule.MuteAfter == 0 || !rule.matches(entry.Text) {
+			continue
+		}
+		recent := muteMatches[rule.Word]
+		if len(recent) == rule.MuteAfter && entry.Arrived.Sub(recent[0]) < rule.Window && entry.Muted == "" {
+			entry.Muted = rule.Word
+		}
+		if len(recent) == rule.MuteAfter {
+			recent = recent[1:]
+		}
+		muteMatches[rule.Word] = append(recent, entry.Arrived)
+	}
+}
+
+// refreshMutes recomputes suppression for the stored lines if the config's
+// mute_after rules have changed.
+func refreshMutes(cfg Config) {
+	logsMutex.Lock()
+	defer logsMutex.Unlock()
+
+	set := muteRules(cfg)
+	if set == muteRuleSet {
+		return
+	}
+	muteRuleSet = set
+	muteMatches = make(map[string][]time.Time)
+	for i := range storedLogs {
+		markMuted(cfg, &storedLogs[i])
+	}
+}
+
+// mutedRun counts consecutive suppressed lines during a render, so each run is
+// shown as one summary line, restarted once per rule window.
+type mutedRun struct {
+	word  string
+	count int
+	start time.Time
+}
+
+// add counts a suppressed entry, ending the current run first if the entry
+// belongs to another rule or falls outside the run's window.
+func (r *mutedRun) add(lines []displayLine, cfg Config, entry LogEntry) []displayLine {
+	window := defaultMuteWindow
+	for _, rule := range cfg.Highlights {
+		if rule.Word == entry.Muted && rule.Window > 0 {
+			window = rule.Window
+		}
+	}
+	if r.count > 0 && (r.word != entry.Muted || entry.Arrived.Sub(r.start) >= window) {
+		lines = r.flush(lines)
+	}
+	if r.count == 0 {
+		r.word, r.start = entry.Muted, entry.Arrived
+	}
+	r.count++
+	return lines
+}
+
+// flush appends the summary of the current run, if any, and resets it.
+func (r *mutedRun) flush(lines []displayLine) []displayLine {
+	if r.count == 0 {
+		return lines
+	}
+	summary := fmt.Sprintf("… %s suppressed, %d more …", r.word, r.count)
+	*r = mutedRun{}
+	return append(lines, displayLine{styledLine: styledText(summary, Dim)})
+}
@@ -0,0 +1,29 @@
+package main
+
+// onlyMatching shows just the parts of lines matched by highlight rules, one
+// per row, like grep -o (--only-matching).
+var onlyMatching bool
+
+// matchRows returns a row for each highlight match in an entry, or for each
+// occurrence of the filter when no rule matches.
+func matchRows(cfg Config, entry LogEntry) []displayLine {
+	line := entry.Text
+	spans := resolveSpans(ruleSpans(line, cfg))
+	if len(spans) == 0 && cfg.Filter != "" {
+		for _, loc := range newHighlightRule(cfg.Filter, "").findAll(line) {
+			spans = append(spans, span{loc[0], loc[1], ""})
+		}
+	}
+
+	prefix := ""
+	if entry.Source != "" {
+		prefix = "[" + entry.Source + "] "
+	}
+	rows := make([]displayLine, 0, len(spans))
+	for _, s := range spans {
+		text := styledText(line[s.start:s.end], s.color).prepend(plainText(prefix))
+		text.source, text.time = entry.Source, entry.Time
+		rows = append(rows, displayLine{styledLine: text, id: entry.ID, isLog: true})
+	}
+	return rows
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// flushWriter buffers output and flushes it according to a --flush policy.
+//
+// Flushing after every line keeps downstream tools like "tail -f" current at
+// the cost of a write per line; block buffering writes in large chunks for
+// throughput but can hold lines back until the buffer fills.
+type flushWriter struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	policy  string
+	every   int  // Lines between flushes; 1 for line buffering, 0 for block buffering
+	frames  bool // Flush at the end of every render, for terminals
+	pending int
+
+	flushes int64
+	written int64
+}
+
+// out is the writer all rendering goes through.
+var out = newFlushWriter("block")
+
+// flushStats reports flush counts on exit (--flush-stats).
+var flushStats bool
+
+// defaultFlushPolicy picks line buffering for pipes and block buffering for
+// files and terminals, where terminals also flush after every render.
+func defaultFlushPolicy() string {
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode().IsRegular() || info.Mode()&os.ModeCharDevice != 0 {
+		return "block"
+	}
+	return "line"
+}
+
+// newFlushWriter creates a stdout writer for a policy: "line", "block", or a
+// number of lines between flushes.
+func newFlushWriter(policy string) *flushWriter {
+	f := &flushWriter{w: bufio.NewWriterSize(os.Stdout, 64*1024), policy: policy}
+	switch policy {
+	case "line":
+		f.every = 1
+	case "block":
+		f.every = 0
+	default:
+		n, err := strconv.Atoi(policy)
+		if err != nil || n < 1 {
+			return nil
+		}
+		f.every = n
+	}
+	f.frames = isTerminal(os.Stdout)
+	return f
+}
+
+// Write buffers p, flushing once enough complete lines have accumulated.
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, err := f.w.Write(p)
+	f.written += int64(n)
+	if err != nil || f.every == 0 {
+		return n, err
+	}
+	f.pending += bytes.Count(p[:n], []byte("\n"))
+	if f.pending >= f.every {
+		return n, f.flushLocked()
+	}
+	return n, nil
+}
+
+// flushLocked writes out buffered data. The caller must hold f.mu.
+func (f *flushWriter) flushLocked() error {
+	f.pending = 0
+	if f.w.Buffered() == 0 {
+		return nil
+	}
+	f.flushes++
+	return f.w.Flush()
+}
+
+// Flush writes out all buffered data.
+func (f *flushWriter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flushLocked()
+}
+
+// endFrame marks the end of a render, flushing if stdout is a terminal so the
+// screen is never left half-drawn.
+func (f *flushWriter) endFrame() {
+	if f.frames {
+		f.Flush()
+	}
+}
+
+// close flushes remaining output and reports statistics if requested.
+func (f *flushWriter) close() {
+	f.Flush()
+	if flushStats {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		fmt.Fprintf(os.Stderr, "output: flush=%s, %d bytes in %d flushes\n", f.policy, f.written, f.flushes)
+	}
+}
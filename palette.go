@@ -0,0 +1,82 @@
+package main
+
+import (
+	"hash/fnv"
+	"regexp"
+	"sync"
+)
+
+// palette holds the colors handed out automatically, e.g. for --color-by.
+var palette = []string{
+	Red, Green, Yellow, Blue, Magenta, Cyan,
+	BrightRed, BrightGreen, BrightYellow, BrightBlue, BrightMagenta, BrightCyan,
+}
+
+// colorForKey returns a stable palette color for a key.
+func colorForKey(key string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return palette[hash.Sum32()%uint32(len(palette))]
+}
+
+// Options for --color-by and --color-by-regex.
+var colorByField string
+var colorByPattern *regexp.Regexp
+
+// colorByMarker is the prefix drawn in a line's group color.
+const colorByMarker = "▌"
+
+// colorByKey extracts the value lines are grouped by for coloring.
+func colorByKey(line string) (string, bool) {
+	return lineKey(line, colorByPattern, colorByField)
+}
+
+// colorByPrefix returns a marker colored by the line's group, or blank padding
+// for lines without a group so the text stays aligned.
+func colorByPrefix(line string) styledLine {
+	if key, ok := colorByKey(line); ok && key != "" {
+		return styledText(colorByMarker, colorForKey(key)).append(plainText(" "))
+	}
+	return plainText("  ")
+}
+
+// autoColorPattern finds tokens that get a color per distinct value (--auto-color-tokens).
+var autoColorPattern *regexp.Regexp
+
+// tokenColors remembers the color handed to each token value this session,
+// for up to maxTokenColors values.
+var tokenColorsMutex sync.Mutex
+var tokenColors = make(map[string]string)
+
+// maxTokenColors caps tokenColors, since tokens like request IDs may never
+// repeat. Values first seen after that are colored by hash, which needs no
+// state and is just as stable.
+const maxTokenColors = 1024
+
+// tokenColor returns the color assigned to a token value, handing out palette
+// colors in order of first appearance so early tokens get distinct colors.
+func tokenColor(token string) string {
+	tokenColorsMutex.Lock()
+	defer tokenColorsMutex.Unlock()
+
+	color, ok := tokenColors[token]
+	if !ok {
+		if len(tokenColors) >= maxTokenColors {
+			return colorForKey(token)
+		}
+		color = palette[len(tokenColors)%len(palette)]
+		tokenColors[token] = color
+	}
+	return color
+}
+
+// tokenSpans colors every --auto-color-tokens match by its value.
+func tokenSpans(line string) []span {
+	var spans []span
+	for _, loc := range autoColorPattern.FindAllStringIndex(line, -1) {
+		if loc[0] < loc[1] {
+			spans = append(spans, span{loc[0], loc[1], tokenColor(line[loc[0]:loc[1]])})
+		}
+	}
+	return spans
+}
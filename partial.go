@@ -0,0 +1,63 @@
+package main
+
+import "sort"
+
+// showPartial shows the unterminated last line of a followed file as a dimmed
+// provisional line until its newline arrives (--show-partial).
+var showPartial bool
+
+// partialLines holds the provisional line of each followed source. Guarded by
+// logsMutex.
+var partialLines = make(map[string]string)
+
+// setPartial updates a source's provisional line, "" removing it, and
+// reprints if it changed.
+func setPartial(source, text string) {
+	if !showPartial {
+		return
+	}
+	logsMutex.Lock()
+	changed := partialLines[source] != text
+	if text == "" {
+		delete(partialLines, source)
+	} else {
+		partialLines[source] = text
+	}
+	logsMutex.Unlock()
+	if changed {
+		reprintLogs()
+	}
+}
+
+// dropPartial removes a source's provisional line without reprinting, for
+// when the completed line is about to be stored.
+func dropPartial(source string) {
+	if !showPartial {
+		return
+	}
+	logsMutex.Lock()
+	delete(partialLines, source)
+	logsMutex.Unlock()
+}
+
+// partialRows renders the provisional lines that pass the filters, below the
+// stored ones. The caller must hold logsMutex.
+func partialRows() []displayLine {
+	sources := make([]string, 0, len(partialLines))
+	for source := range partialLines {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	var rows []displayLine
+	for _, source := range sources {
+		text := partialLines[source]
+		if _, shown := filterAndHighlight(text); !shown {
+			continue
+		}
+		entry := LogEntry{Text: text, Source: source}
+		row := decorate(entry, plainText(stripANSI(text)))
+		rows = append(rows, displayLine{styledLine: styledText(row.text+"…", Dim)})
+	}
+	return rows
+}
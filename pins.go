@@ -0,0 +1,73 @@
+package main
+
+import "fmt"
+
+// pinnedEntries are lines kept above the scrolling view, guarded by viewMutex.
+// They are copies, so they survive config reloads and buffer changes.
+var pinnedEntries []LogEntry
+
+// togglePin pins the focused line, or unpins it if it is already pinned.
+func togglePin() {
+	viewMutex.Lock()
+	id := focusID
+	viewMutex.Unlock()
+
+	logsMutex.RLock()
+	var entry LogEntry
+	found := false
+	for _, log := range storedLogs {
+		if log.ID == id {
+			entry, found = log, true
+			break
+		}
+	}
+
+	logsMutex.RUnlock()
+
+	viewMutex.Lock()
+	unpinned := false
+	for i, pinned := range pinnedEntries {
+		if pinned.ID == id {
+			pinnedEntries = append(pinnedEntries[:i], pinnedEntries[i+1:]...)
+			unpinned = true
+			break
+		}
+	}
+	if !unpinned && found {
+		pinnedEntries = append(pinnedEntries, entry)
+	}
+	viewMutex.Unlock()
+
+	reprintLogs()
+}
+
+// clearPins removes every pinned line.
+func clearPins() {
+	viewMutex.Lock()
+	pinnedEntries = nil
+	viewMutex.Unlock()
+	reprintLogs()
+}
+
+// pinnedRows renders the pinned region, at most maxRows tall including its
+// divider, showing the most recent pins. The caller must hold viewMutex.
+func pinnedRows(maxRows, cols int) []string {
+	if len(pinnedEntries) == 0 || maxRows < 2 {
+		return nil
+	}
+
+	configMutex.RLock()
+	cfg := currentConfig
+	configMutex.RUnlock()
+
+	shown := pinnedEntries
+	if len(shown) > maxRows-1 {
+		shown = shown[len(shown)-(maxRows-1):]
+	}
+	var rows []string
+	for _, entry := range shown {
+		rows = append(rows, truncateANSI(Reverse+"*"+Reset+" "+decorate(entry, highlightLine(entry.Text, cfg)).ansi(), cols))
+	}
+	divider := fmt.Sprintf("──── %d pinned ────", len(pinnedEntries))
+	return append(rows, Dim+truncateANSI(divider, cols)+Reset)
+}
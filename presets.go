@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// presetExt is the extension of preset files, which hold a config in the
+// config file format.
+const presetExt = ".txt"
+
+// presetDir returns the preset store, ~/.config/loggo/presets on Linux.
+func presetDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "loggo", "presets"), nil
+}
+
+// presetPath returns the file of a named preset.
+func presetPath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid preset name %q", name)
+	}
+	dir, err := presetDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+presetExt), nil
+}
+
+// listPresets returns the names of the saved presets, sorted.
+func listPresets() ([]string, error) {
+	dir, err := presetDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), presetExt); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// currentConfigFile returns the config file being watched, which loading a
+// preset changes.
+func currentConfigFile() string {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return configFile
+}
+
+// savePreset stores the current config as a preset named at a prompt.
+func savePreset() {
+	name, ok := prompt("Save preset as: ")
+	if !ok || strings.TrimSpace(name) == "" {
+		return
+	}
+	path, err := presetPath(strings.TrimSpace(name))
+	if err != nil {
+		showMessage("Error saving preset: %v", err)
+		return
+	}
+	if _, err := os.Stat(path); err == nil && !confirm("Preset "+name+" exists, overwrite?") {
+		return
+	}
+
+	configMutex.RLock()
+	content := formatConfig(currentConfig)
+	configMutex.RUnlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		showMessage("Error saving preset: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		showMessage("Error saving preset: %v", err)
+		return
+	}
+	showMessage("Saved preset %s", name)
+}
+
+// loadPreset switches to a preset picked at a prompt. The preset file then
+// becomes the watched config file, so edits to it apply live.
+func loadPreset() {
+	names, err := listPresets()
+	if err != nil {
+		showMessage("Error listing presets: %v", err)
+		return
+	}
+	if len(names) == 0 {
+		showMessage("No presets saved yet; ctrl-s saves one")
+		return
+	}
+	name, ok := prompt("Load preset (" + strings.Join(names, ", ") + "): ")
+	if !ok || strings.TrimSpace(name) == "" {
+		return
+	}
+	path, err := presetPath(strings.TrimSpace(name))
+	if err == nil {
+		_, err = os.Stat(path)
+	}
+	if err != nil {
+		showMessage("Error loading preset: %v", err)
+		return
+	}
+
+	configMutex.Lock()
+	configFile = path
+	configMutex.Unlock()
+	reloadConfig(path)
+	showMessage("Loaded preset %s", strings.TrimSpace(name))
+}
@@ -0,0 +1,31 @@
+package main
+
+import "strings"
+
+// handleCR collapses carriage-return progress updates (--handle-cr).
+var handleCR bool
+
+// collapseCarriageReturns renders a line the way a terminal would: each "\r"
+// returns to the start of the line and later text overwrites earlier text, so
+// a progress bar collapses to its final frame.
+func collapseCarriageReturns(line string) string {
+	if !strings.Contains(line, "\r") {
+		return line
+	}
+
+	var screen []rune
+	col := 0
+	for _, r := range line {
+		if r == '\r' {
+			col = 0
+			continue
+		}
+		if col < len(screen) {
+			screen[col] = r
+		} else {
+			screen = append(screen, r)
+		}
+		col++
+	}
+	return string(screen)
+}
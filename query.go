@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A query filters structured lines by their fields (--query), for example:
+//
+//	level = "error" and status >= 500 and not path ~ "/health"
+//
+// Operators are =, !=, <, <=, >, >=, ~ (regex match) and !~. A bare field name
+// tests that the field exists. "not" binds tighter than "and", which binds
+// tighter than "or", and parentheses group. A comparison against a missing
+// field is false. Values compare as numbers when both sides are numeric.
+
+// activeQuery is the parsed --query, or nil when none was given.
+var activeQuery queryNode
+
+// queryNode is a node of a parsed query.
+type queryNode interface {
+	eval(fields map[string]string) bool
+}
+
+type orNode struct{ left, right queryNode }
+type andNode struct{ left, right queryNode }
+type notNode struct{ operand queryNode }
+type existsNode struct{ field string }
+
+type compareNode struct {
+	field string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (n orNode) eval(fields map[string]string) bool {
+	return n.left.eval(fields) || n.right.eval(fields)
+}
+
+func (n andNode) eval(fields map[string]string) bool {
+	return n.left.eval(fields) && n.right.eval(fields)
+}
+
+func (n notNode) eval(fields map[string]string) bool {
+	return !n.operand.eval(fields)
+}
+
+func (n existsNode) eval(fields map[string]string) bool {
+	_, ok := fields[n.field]
+	return ok
+}
+
+func (n compareNode) eval(fields map[string]string) bool {
+	actual, ok := fields[n.field]
+	if !ok {
+		return false
+	}
+
+	switch n.op {
+	case "~":
+		return n.re.MatchString(actual)
+	case "!~":
+		return !n.re.MatchString(actual)
+	}
+
+	// Compare numerically when both sides are numbers, otherwise as strings.
+	cmp := strings.Compare(actual, n.value)
+	a, errA := strconv.ParseFloat(actual, 64)
+	b, errB := strconv.ParseFloat(n.value, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case a < b:
+			cmp = -1
+		case a > b:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	switch n.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
+
+// queryToken is a lexical token of a query.
+type queryToken struct {
+	kind string // "ident", "string", "number", "op", "(", ")", or "end"
+	text string
+	pos  int
+}
+
+// queryOperators lists comparison operators, longest first.
+var queryOperators = []string{"<=", ">=", "!=", "!~", "=", "<", ">", "~"}
+
+// lexQuery splits a query into tokens. Positions are byte offsets.
+func lexQuery(src string) ([]queryToken, error) {
+	var tokens []queryToken
+	i := 0
+	for i < len(src) {
+		c, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c == '(' || c == ')':
+			tokens = append(tokens, queryToken{string(c), string(c), i})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, queryToken{"string", text, i})
+			i = end + 1
+		case isASCIIDigit(c) || (c == '-' && i+1 < len(src) && isASCIIDigit(rune(src[i+1]))):
+			end := i + 1
+			for end < len(src) && (isASCIIDigit(rune(src[end])) || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, queryToken{"number", src[i:end], i})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i + size
+			for end < len(src) {
+				r, n := utf8.DecodeRuneInString(src[end:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_.-", r) {
+					break
+				}
+				end += n
+			}
+			tokens = append(tokens, queryToken{"ident", src[i:end], i})
+			i = end
+		default:
+			matched := false
+			for _, op := range queryOperators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, queryToken{"op", op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+		}
+	}
+	return append(tokens, queryToken{"end", "", len(src)}), nil
+}
+
+// isASCIIDigit reports whether c is 0-9, the digits of a number token;
+// unicode.IsDigit also takes digits of other scripts, which ParseFloat doesn't.
+func isASCIIDigit(c rune) bool {
+	return '0' <= c && c <= '9'
+}
+
+// queryParser is a recursive-descent parser over query tokens.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "end" {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword, consuming it if so.
+func (p *queryParser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == "ident" && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.keyword("not") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != ")" {
+			return nil, fmt.Errorf("expected \")\" at position %d", closing.pos)
+		}
+		return node, nil
+	case "ident":
+		if p.peek().kind != "op" {
+			return existsNode{tok.text}, nil
+		}
+		op := p.next().text
+		value := p.next()
+		if value.kind != "string" && value.kind != "number" && value.kind != "ident" {
+			return nil, fmt.Errorf("expected a value after %q at position %d", op, value.pos)
+		}
+		node := compareNode{field: tok.text, op: op, value: value.text}
+		if op == "~" || op == "!~" {
+			re, err := regexp.Compile(value.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex at position %d: %v", value.pos, err)
+			}
+			node.re = re
+		}
+		return node, nil
+	case "end":
+		return nil, fmt.Errorf("unexpected end of query")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+}
+
+// parseQuery parses a query expression.
+func parseQuery(src string) (queryNode, error) {
+	tokens, err := lexQuery(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "end" {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return node, nil
+}
+
+// queryRejects reports whether a structured line fails the active query.
+// Unstructured lines are left to the plain filters.
+func queryRejects(line string) bool {
+	if activeQuery == nil {
+		return false
+	}
+	fields := parseFields(line)
+	return fields != nil && !activeQuery.eval(fields)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueryEval(t *testing.T) {
+	fields := map[string]string{
+		"level":  "error",
+		"status": "503",
+		"path":   "/api/users",
+		"user":   "jörg",
+		"größe":  "10",
+		"delta":  "-3",
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{`level = "error"`, true},
+		{`level != "error"`, false},
+		{`level = "error" and status >= 500`, true},
+		{`level = "error" and status < 500`, false},
+
+		// "and" binds tighter than "or".
+		{`level = "error" or status < 500 and path ~ "/health"`, true},
+		{`status < 500 and path ~ "/health" or level = "error"`, true},
+		{`level = "info" or status >= 500 and path ~ "/health"`, false},
+		{`level = "error" AND status = 503 OR level = "info"`, true},
+
+		// "not" binds tighter than "and".
+		{`not level = "error" and status = 404`, false},
+		{`not level = "info" and status = 503`, true},
+		{`not not level = "error"`, true},
+		{`not (level = "error" and status = 404)`, true},
+
+		// Parentheses group.
+		{`(level = "error" or status < 500) and path ~ "/health"`, false},
+		{`level = "info" and (status < 500 or path ~ "^/api")`, false},
+		{`(level = "info" or (status >= 500 and path ~ "^/api"))`, true},
+
+		// Numbers compare as numbers, anything else as strings.
+		{`status > 60`, true},
+		{`status = 503.0`, true},
+		{`delta < 0`, true},
+		{`delta > -5`, true},
+		{`level > "debug"`, true},
+		{`level < "debug"`, false},
+		{`path = 503`, false},
+
+		// Regex matches.
+		{`path ~ "^/api/"`, true},
+		{`path !~ "users$"`, false},
+		{`path ~ "/health"`, false},
+		{`not path ~ "/health"`, true},
+		{`user ~ "^jö"`, true},
+
+		// Missing fields and existence.
+		{`status`, true},
+		{`missing`, false},
+		{`not missing`, true},
+		{`missing = 1`, false},
+		{`missing != 1`, false},
+
+		// Non-ASCII names and bare words.
+		{`größe >= 10`, true},
+		{`user = jörg`, true},
+		{`user = jorg`, false},
+	}
+	for _, tt := range tests {
+		node, err := parseQuery(tt.query)
+		if err != nil {
+			t.Errorf("parseQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if got := node.eval(fields); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string // Prefix of the error
+	}{
+		{``, "unexpected end of query"},
+		{`level = "error" and`, "unexpected end of query"},
+		{`level =`, `expected a value after "=" at position 7`},
+		{`level = (`, `expected a value after "=" at position 8`},
+		{`(level = "error"`, `expected ")" at position 16`},
+		{`level = "error`, "unterminated string at position 8"},
+		{`level = "\q"`, "invalid string at position 8"},
+		{`path ~ "("`, "invalid regex at position 7"},
+		{`level = error status`, `unexpected "status" at position 14`},
+		{`= 1`, `unexpected "=" at position 0`},
+		{`level # 1`, `unexpected '#' at position 6`},
+		{`größe # 1`, `unexpected '#' at position 8`},
+		{`level = 1 )`, `unexpected ")" at position 10`},
+	}
+	for _, tt := range tests {
+		_, err := parseQuery(tt.query)
+		if err == nil {
+			t.Errorf("parseQuery(%q) succeeded, want error %q", tt.query, tt.want)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("parseQuery(%q) error %q, want %q", tt.query, err, tt.want)
+		}
+	}
+}
+
+// TestQueryRejects checks --query against whole lines: structured lines are
+// judged by their fields, and others are left to the plain filters.
+func TestQueryRejects(t *testing.T) {
+	saved := activeQuery
+	t.Cleanup(func() { activeQuery = saved })
+	node, err := parseQuery(`level = "error" and status >= 500`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeQuery = node
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{`{"level": "error", "status": 503}`, false},
+		{`{"level": "error", "status": 404}`, true},
+		{`level=error status=500`, false},
+		{`level=info status=500`, true},
+		{`plain text error`, false},
+	}
+	for _, tt := range tests {
+		if got := queryRejects(tt.line); got != tt.want {
+			t.Errorf("queryRejects(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordPath is the file every raw input line is copied to verbatim, line
+// endings and all (--record), so a session can be played back later with
+// --replay.
+var recordPath string
+
+// recordTimingSuffix names the file kept next to a recording with when each
+// of its lines arrived and from which input, so --replay can keep the timing.
+const recordTimingSuffix = ".timing"
+
+var recordMutex sync.Mutex
+var recordFile *os.File
+var recordTimingFile *os.File
+var lastRecorded time.Time // Arrival of the previous recorded line
+
+// startRecording creates the record file and its timing file, and arranges
+// for them to be synced and closed at exit.
+func startRecording(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	timing, err := os.Create(path + recordTimingSuffix)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	recordFile, recordTimingFile = f, timing
+	lastRecorded = time.Now()
+	atExit(func() {
+		recordMutex.Lock()
+		defer recordMutex.Unlock()
+		closeRecording()
+	})
+	return nil
+}
+
+// recordLine appends a raw input line, with its line ending if it has one, to
+// the record file, and its timing to the timing file. Lines are written
+// unbuffered so a crash loses at most the line in flight.
+func recordLine(source, raw string) {
+	recordMutex.Lock()
+	defer recordMutex.Unlock()
+	if recordFile == nil {
+		return
+	}
+	encoded := encodeRaw(raw)
+	now := time.Now()
+	_, err := recordFile.WriteString(encoded)
+	if err == nil {
+		// The delay since the previous line, the line's size in the record
+		// file, and its input.
+		_, err = fmt.Fprintf(recordTimingFile, "%.6f %d %s\n", now.Sub(lastRecorded).Seconds(), len(encoded), source)
+	}
+	lastRecorded = now
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing --record file:", err)
+		closeRecording()
+	}
+}
+
+// closeRecording syncs and closes the record files. The caller must hold
+// recordMutex.
+func closeRecording() {
+	if recordFile == nil {
+		return
+	}
+	for _, f := range []*os.File{recordFile, recordTimingFile} {
+		if err := f.Sync(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error syncing --record file:", err)
+		}
+		f.Close()
+	}
+	recordFile, recordTimingFile = nil, nil
+}
+
+// scanRawLines is bufio.ScanLines keeping each line's ending, so the line can
+// be recorded as it arrived.
+func scanRawLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// trimLineEnding removes the "\n" or "\r\n" scanRawLines leaves on a line.
+func trimLineEnding(raw string) string {
+	if len(raw) > 0 && raw[len(raw)-1] == '\n' {
+		raw = raw[:len(raw)-1]
+		if len(raw) > 0 && raw[len(raw)-1] == '\r' {
+			raw = raw[:len(raw)-1]
+		}
+	}
+	return raw
+}
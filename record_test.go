@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestScanRawLines checks that lines keep their endings for --record, and lose
+// them as ScanLines would for display.
+func TestScanRawLines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("one\r\ntwo\n\nlast"))
+	scanner.Split(scanRawLines)
+	var raw, trimmed []string
+	for scanner.Scan() {
+		raw = append(raw, scanner.Text())
+		trimmed = append(trimmed, trimLineEnding(scanner.Text()))
+	}
+	if want := []string{"one\r\n", "two\n", "\n", "last"}; !reflect.DeepEqual(raw, want) {
+		t.Errorf("raw lines %q, want %q", raw, want)
+	}
+	if want := []string{"one", "two", "", "last"}; !reflect.DeepEqual(trimmed, want) {
+		t.Errorf("trimmed lines %q, want %q", trimmed, want)
+	}
+}
+
+// TestRecordTiming checks that recordLine writes lines verbatim and a timing
+// entry for each that --replay reads back.
+func TestRecordTiming(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "capture.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timing, err := os.Create(path + recordTimingSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordFile, recordTimingFile, lastRecorded = f, timing, time.Now()
+	t.Cleanup(func() {
+		recordMutex.Lock()
+		defer recordMutex.Unlock()
+		closeRecording()
+	})
+
+	lines := []struct{ source, raw string }{
+		{"", "one\r\n"},
+		{"app.log", "two\n"},
+		{"my app.log", "last"},
+	}
+	for _, line := range lines {
+		recordLine(line.source, line.raw)
+	}
+	recordMutex.Lock()
+	closeRecording()
+	recordMutex.Unlock()
+
+	recorded, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(recorded), "one\r\ntwo\nlast"; got != want {
+		t.Errorf("recording %q, want %q", got, want)
+	}
+	entries, err := os.ReadFile(path + recordTimingSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSuffix(string(entries), "\n"), "\n")
+	if len(rows) != len(lines) {
+		t.Fatalf("timing has %d entries, want %d: %q", len(rows), len(lines), entries)
+	}
+	for i, row := range rows {
+		delay, size, source, err := parseTimingEntry(row)
+		if err != nil {
+			t.Fatalf("parseTimingEntry(%q): %v", row, err)
+		}
+		if delay < 0 || size != len(lines[i].raw) || source != lines[i].source {
+			t.Errorf("entry %q = %v, %d, %q, want size %d from %q", row, delay, size, source, len(lines[i].raw), lines[i].source)
+		}
+	}
+}
+
+func TestParseTimingEntry(t *testing.T) {
+	delay, size, source, err := parseTimingEntry("1.500000 12 app.log")
+	if err != nil || delay != 1500*time.Millisecond || size != 12 || source != "app.log" {
+		t.Errorf("parseTimingEntry = %v, %d, %q, %v", delay, size, source, err)
+	}
+	for _, entry := range []string{"", "x 1 ", "-1 1 ", "0.1 x ", "0.1 -2 "} {
+		if _, _, _, err := parseTimingEntry(entry); err == nil {
+			t.Errorf("parseTimingEntry(%q) succeeded, want an error", entry)
+		}
+	}
+}
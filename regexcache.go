@@ -0,0 +1,116 @@
+package main
+
+import (
+	"container/list"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// regexCacheSize caps how many compiled highlight regexes are kept
+// (--regex-cache); 0 keeps every one. Rules are only checked for syntax when
+// the config loads and are compiled the first time a line contains their
+// required literal, so a large config of rarely matching rules costs little
+// until its rules match.
+var regexCacheSize int
+
+// compiledRegexes holds compiled rule regexes by source, most recently used
+// first.
+var compiledRegexes = regexCache{entries: make(map[string]*list.Element)}
+
+// neverMatches stands in for a regex that passed validation but failed to
+// compile, which only happens for programs past the compiler's size limit.
+var neverMatches = regexp.MustCompile(`[^\x00-\x{10FFFF}]`)
+
+type regexCache struct {
+	mutex   sync.Mutex
+	order   list.List
+	entries map[string]*list.Element
+}
+
+type cachedRegex struct {
+	source string
+	re     *regexp.Regexp
+}
+
+// get returns the compiled regex for source, compiling it if it isn't cached
+// and evicting the least recently used one beyond the cap.
+func (c *regexCache) get(source string) *regexp.Regexp {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if e, ok := c.entries[source]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*cachedRegex).re
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		re = neverMatches
+	}
+	c.entries[source] = c.order.PushFront(&cachedRegex{source, re})
+	if regexCacheSize > 0 && c.order.Len() > regexCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedRegex).source)
+	}
+	return re
+}
+
+// checkRegex validates a regex without compiling it.
+func checkRegex(source string) (*syntax.Regexp, error) {
+	return syntax.Parse(source, syntax.Perl)
+}
+
+// requiredLiteral returns the longest literal that every match of a parsed
+// regex contains, and whether it matches case-insensitively, or "" if there is
+// no such literal.
+func requiredLiteral(re *syntax.Regexp) (string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return string(re.Rune), re.Flags&syntax.FoldCase != 0
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return requiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		best, fold := "", false
+		for _, sub := range re.Sub {
+			if literal, f := requiredLiteral(sub); len(literal) > len(best) {
+				best, fold = literal, f
+			}
+		}
+		return best, fold
+	}
+	return "", false
+}
+
+// mayContain reports whether a line may contain a literal, so that a regex
+// requiring it is worth compiling and running. Case-insensitive checks are
+// only done for ASCII literals, and give way on lines with the non-ASCII
+// runes that fold to ASCII letters (ſ and the Kelvin sign).
+func mayContain(line, literal string, fold bool) bool {
+	if literal == "" {
+		return true
+	}
+	if !fold {
+		return strings.Contains(line, literal)
+	}
+	for i := 0; i < len(literal); i++ {
+		if literal[i] >= utf8.RuneSelf {
+			return true
+		}
+	}
+	if strings.ContainsAny(line, "\u017f\u212a") {
+		return true
+	}
+	for i := 0; i+len(literal) <= len(line); i++ {
+		if strings.EqualFold(line[i:i+len(literal)], literal) {
+			return true
+		}
+	}
+	return false
+}
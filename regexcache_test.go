@@ -0,0 +1,148 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"testing"
+)
+
+func TestRequiredLiteral(t *testing.T) {
+	tests := []struct {
+		source  string
+		literal string
+		fold    bool
+	}{
+		{`error`, "error", false},
+		{`(?i)error`, "ERROR", true},
+		{`took \d+ms`, "took ", false},
+		{`\d+ms elapsed`, "ms elapsed", false},
+		{`(timeout)`, "timeout", false},
+		{`(?P<code>5\d\d) from upstream`, " from upstream", false},
+		{`(?:panic)+`, "panic", false},
+		{`(?:oom){2,}`, "oom", false},
+		{`(?:oom){0,3}`, "", false},
+		{`(?:oom)*`, "", false},
+		{`(?:oom)?killer`, "killer", false},
+		{`panic|oom`, "", false},
+		{`error|errno`, "err", false},
+		{`a(?i:bcd)`, "BCD", true},
+		{`(?i:a)bcd`, "bcd", false},
+		{`.*`, "", false},
+		{`\w+=\S+`, "=", false},
+	}
+	for _, tt := range tests {
+		re, err := checkRegex(tt.source)
+		if err != nil {
+			t.Fatalf("checkRegex(%q): %v", tt.source, err)
+		}
+		literal, fold := requiredLiteral(re)
+		if literal != tt.literal || fold != tt.fold {
+			t.Errorf("requiredLiteral(%q) = %q, %v, want %q, %v", tt.source, literal, fold, tt.literal, tt.fold)
+		}
+	}
+}
+
+func TestMayContain(t *testing.T) {
+	tests := []struct {
+		name, line, literal string
+		fold                bool
+		want                bool
+	}{
+		{"no literal", "anything", "", false, true},
+		{"no literal in an empty line", "", "", true, true},
+		{"case-sensitive hit", "an error here", "error", false, true},
+		{"case-sensitive miss on case", "an ERROR here", "error", false, false},
+		{"case-sensitive miss", "all good", "error", false, false},
+		{"folded hit", "an ERROR here", "error", true, true},
+		{"folded hit at the end", "ERROR", "rOr", true, true},
+		{"folded miss", "all good", "error", true, false},
+		{"literal longer than the line", "err", "error", true, false},
+		{"non-ASCII literal", "STRASSE", "straße", true, true},
+		{"non-ASCII case-sensitive literal", "Straße", "straße", false, false},
+		{"long s folds to s", "ſtatus", "status", true, true},
+		{"Kelvin sign folds to k", "5K", "k", true, true},
+		{"non-ASCII line without folding runes", "größe", "k", true, false},
+	}
+	for _, tt := range tests {
+		if got := mayContain(tt.line, tt.literal, tt.fold); got != tt.want {
+			t.Errorf("%s: mayContain(%q, %q, %v) = %v, want %v", tt.name, tt.line, tt.literal, tt.fold, got, tt.want)
+		}
+	}
+}
+
+// TestPrefilterKeepsMatches checks that no line a regex matches is skipped by
+// the literal check, which would silently drop its highlights.
+func TestPrefilterKeepsMatches(t *testing.T) {
+	sources := []string{
+		`(?i)error`, `(?i)(panic|oom)`, `(?i)oom killer`, `took \d+ms`,
+		`(?P<method>GET|POST) (?P<path>\S+)`, `(?i)status=5\d\d`, `(?i)k`,
+		`(?i)straße`, `(?:ab){2,}c`, `(?i)sk`,
+	}
+	lines := []string{
+		"ERROR disk full", "Panic: OOM", "oom KILLER invoked", "took 204ms",
+		"GET /index", "STATUS=503", "5K", "ſtatus=500", "STRASSE",
+		"ababc", "ABABC", "ſK", "nothing to see",
+	}
+	for _, source := range sources {
+		re, err := checkRegex(source)
+		if err != nil {
+			t.Fatalf("checkRegex(%q): %v", source, err)
+		}
+		literal, fold := requiredLiteral(re)
+		compiled := regexp.MustCompile(source)
+		for _, line := range lines {
+			if compiled.MatchString(line) && !mayContain(line, literal, fold) {
+				t.Errorf("%q matches %q, but its literal %q (fold %v) was ruled out", source, line, literal, fold)
+			}
+		}
+	}
+}
+
+// BenchmarkLazyVsEager compares loading a large config of rarely matching
+// color-first rules and highlighting lines with them, compiling every rule up
+// front against compiling each rule the first time its literal shows up.
+func BenchmarkLazyVsEager(b *testing.B) {
+	var rules []HighlightRule
+	for i := range 500 {
+		rule, err := parseColorRule("red", fmt.Sprintf("(service-%03d (timeout|refused))", i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		rules = append(rules, rule)
+	}
+	var lines []string
+	for i := range 200 {
+		line := fmt.Sprintf("level=info request_id=%d took %dms", i, i%50)
+		if i%20 == 0 {
+			line += " service-007 timeout"
+		}
+		lines = append(lines, line)
+	}
+
+	b.Run("eager", func(b *testing.B) {
+		for range b.N {
+			compiled := make([]*regexp.Regexp, len(rules))
+			for i, rule := range rules {
+				compiled[i] = regexp.MustCompile(rule.source)
+			}
+			for _, line := range lines {
+				for _, re := range compiled {
+					re.FindAllStringIndex(line, -1)
+				}
+			}
+		}
+	})
+	b.Run("lazy", func(b *testing.B) {
+		for range b.N {
+			cache := &regexCache{entries: make(map[string]*list.Element)}
+			for _, line := range lines {
+				for _, rule := range rules {
+					if mayContain(line, rule.literal, rule.fold) {
+						cache.get(rule.source).FindAllStringIndex(line, -1)
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// replayPath is a --record file to play back as input (--replay). Its lines
+// arrive with the delays and from the inputs kept in its timing file, or all
+// at once, in order, when there is no timing file.
+var replayPath string
+
+// replayRecording plays back a recording, returning once every line is in.
+func replayRecording(file *os.File) {
+	defer file.Close()
+	timing, err := os.Open(file.Name() + recordTimingSuffix)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintln(os.Stderr, "Error opening --replay timing, replaying without delays:", err)
+		}
+		replayUntimed(file)
+		return
+	}
+	defer timing.Close()
+
+	recording := bufio.NewReader(file)
+	entries := bufio.NewScanner(timing)
+	for entries.Scan() {
+		delay, size, source, err := parseTimingEntry(entries.Text())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error in --replay timing, replaying the rest without delays:", err)
+			break
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(recording, chunk); err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading --replay file: the recording is shorter than its timing")
+			return
+		}
+		time.Sleep(delay)
+		appendRawLog(source, decodeBytes(chunk))
+	}
+	// Lines past the end of the timing, as after a crash between the writes.
+	replayUntimed(recording)
+}
+
+// replayUntimed reads the rest of a recording at once.
+func replayUntimed(r io.Reader) {
+	scanner := bufio.NewScanner(decodeInput(r))
+	scanner.Split(scanRawLines)
+	readLogs(scanner, "")
+}
+
+// parseTimingEntry parses a timing file line written by recordLine: the delay
+// in seconds since the previous line, the line's size in bytes, and its input.
+func parseTimingEntry(entry string) (time.Duration, int, string, error) {
+	delayText, rest, _ := strings.Cut(entry, " ")
+	sizeText, source, _ := strings.Cut(rest, " ")
+	seconds, err := strconv.ParseFloat(delayText, 64)
+	if err != nil || seconds < 0 {
+		return 0, 0, "", fmt.Errorf("invalid delay %q", delayText)
+	}
+	size, err := strconv.Atoi(sizeText)
+	if err != nil || size < 0 {
+		return 0, 0, "", fmt.Errorf("invalid size %q", sizeText)
+	}
+	return time.Duration(seconds * float64(time.Second)), size, source, nil
+}
+
+// sameFile reports whether two paths name the same existing file.
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// resumeSaveInterval is how often the read offset is persisted while reading.
+const resumeSaveInterval = 5 * time.Second
+
+// Options for --resume.
+var resumeEnabled bool
+var resumeStatePath string
+
+// resumeStateMutex serializes read-modify-write cycles of the state file.
+var resumeStateMutex sync.Mutex
+
+// defaultResumeStatePath returns the state file location in the user cache directory.
+func defaultResumeStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".loggo-offsets.json"
+	}
+	return filepath.Join(dir, "loggo", "offsets.json")
+}
+
+// loadOffsets reads the saved offsets, keyed by absolute input path.
+func loadOffsets() map[string]int64 {
+	offsets := make(map[string]int64)
+	content, err := os.ReadFile(resumeStatePath)
+	if err != nil {
+		return offsets
+	}
+	if err := json.Unmarshal(content, &offsets); err != nil {
+		fmt.Fprintln(os.Stderr, "Error parsing resume state, starting over:", err)
+	}
+	return offsets
+}
+
+// saveOffset atomically records the offset for one input in the state file.
+func saveOffset(key string, offset int64) error {
+	resumeStateMutex.Lock()
+	defer resumeStateMutex.Unlock()
+
+	offsets := loadOffsets()
+	offsets[key] = offset
+	content, err := json.MarshalIndent(offsets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(resumeStatePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".offsets-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), resumeStatePath)
+}
+
+// resumeFrom seeks the file to its saved offset and arranges for the offset of
+// consumed lines to be saved periodically and on shutdown. It must be called
+// before the scanner is first used.
+func resumeFrom(file *os.File, scanner *bufio.Scanner) {
+	key, err := filepath.Abs(file.Name())
+	if err != nil {
+		key = file.Name()
+	}
+
+	offset := loadOffsets()[key]
+	if info, err := file.Stat(); err == nil && offset > info.Size() {
+		// The file was truncated or replaced since the last run.
+		offset = 0
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		fmt.Fprintln(os.Stderr, "Error seeking to resume offset:", err)
+		offset = 0
+		file.Seek(0, io.SeekStart)
+	}
+
+	// Count the bytes of each line the scanner hands out, including its newline,
+	// rather than what has been read ahead into the scanner's buffer.
+	var consumed atomic.Int64
+	consumed.Store(offset)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := scanRawLines(data, atEOF)
+		if token != nil {
+			consumed.Add(int64(advance))
+		}
+		return advance, token, err
+	})
+
+	var saveMutex sync.Mutex
+	saved := offset
+	save := func() {
+		saveMutex.Lock()
+		defer saveMutex.Unlock()
+		current := consumed.Load()
+		if current == saved {
+			return
+		}
+		if err := saveOffset(key, current); err != nil {
+			fmt.Fprintln(os.Stderr, "Error saving resume offset:", err)
+			return
+		}
+		saved = current
+	}
+
+	atExit(save)
+	go func() {
+		for range time.Tick(resumeSaveInterval) {
+			save()
+		}
+	}()
+}
@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+// retainFor evicts lines older than this from the buffer (--retain).
+var retainFor time.Duration
+
+// retainByTimestamp ages lines by their parsed timestamps rather than their
+// arrival (--retain-by-timestamp). Opening an old file with this evicts its
+// lines as they are read.
+var retainByTimestamp bool
+
+// entryAge returns how old an entry is: by its arrival, or with
+// --retain-by-timestamp by its parsed timestamp when it has one.
+func entryAge(entry LogEntry, now time.Time) time.Duration {
+	if retainByTimestamp && !entry.Time.IsZero() {
+		return now.Sub(entry.Time)
+	}
+	return now.Sub(entry.Arrived)
+}
+
+// evictExpired drops lines older than retainFor from the front of the buffer,
+// reporting whether any were dropped. The caller must hold logsMutex for
+// writing.
+func evictExpired() bool {
+	if retainFor <= 0 {
+		return false
+	}
+	now := time.Now()
+	n := 0
+	for n < len(storedLogs) && entryAge(storedLogs[n], now) > retainFor {
+		n++
+	}
+	if n == 0 {
+		return false
+	}
+	storedLogs = append(storedLogs[:0:0], storedLogs[n:]...)
+	return true
+}
+
+// watchRetention evicts expired lines once a second, so the buffer keeps
+// shrinking while the input is quiet.
+func watchRetention() {
+	for range time.Tick(time.Second) {
+		logsMutex.Lock()
+		evicted := evictExpired()
+		logsMutex.Unlock()
+		if evicted {
+			reprintLogs()
+		}
+	}
+}
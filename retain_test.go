@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEntryAge checks that --retain ages lines by arrival, so an old file
+// stays on screen, unless --retain-by-timestamp asks for their timestamps.
+func TestEntryAge(t *testing.T) {
+	saved := retainByTimestamp
+	t.Cleanup(func() { retainByTimestamp = saved })
+
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	old := LogEntry{Arrived: now.Add(-time.Second), Time: now.Add(-24 * time.Hour)}
+	untimed := LogEntry{Arrived: now.Add(-time.Minute)}
+
+	retainByTimestamp = false
+	if got := entryAge(old, now); got != time.Second {
+		t.Errorf("entryAge of an old line = %v, want its arrival age 1s", got)
+	}
+	retainByTimestamp = true
+	if got := entryAge(old, now); got != 24*time.Hour {
+		t.Errorf("with --retain-by-timestamp, entryAge of an old line = %v, want 24h", got)
+	}
+	if got := entryAge(untimed, now); got != time.Minute {
+		t.Errorf("with --retain-by-timestamp, entryAge of a line without a timestamp = %v, want 1m", got)
+	}
+}
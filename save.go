@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// saveBuffer writes the lines currently in the view to a file chosen at a
+// prompt, as plain text, with the ANSI colors shown on screen, or as HTML.
+func saveBuffer() {
+	path, ok := prompt("Save view to: ")
+	if !ok || strings.TrimSpace(path) == "" {
+		return
+	}
+	path = strings.TrimSpace(path)
+
+	answer, ok := prompt("Format, (p)lain, (a)nsi, or (h)tml: ")
+	if !ok {
+		return
+	}
+	var format Formatter = plainFormatter{}
+	switch {
+	case strings.HasPrefix(strings.ToLower(answer), "a"):
+		format = ansiFormatter{}
+	case strings.HasPrefix(strings.ToLower(answer), "h"):
+		format = htmlFormatter{}
+	}
+
+	if _, err := os.Stat(path); err == nil && !confirm(path+" exists, overwrite?") {
+		return
+	}
+
+	logsMutex.RLock()
+	lines := renderLines()
+	logsMutex.RUnlock()
+
+	if err := writeBufferFile(path, lines, format); err != nil {
+		showMessage("Error saving view: %v", err)
+		return
+	}
+	showMessage("Saved %d lines to %s", len(lines), path)
+}
+
+// writeBufferFile writes rendered lines to a file in a format.
+func writeBufferFile(path string, lines []displayLine, format Formatter) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	format.Begin(w)
+	writeLines(w, format, lines)
+	format.End(w)
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// searchDebounce is how long typing in the search prompt must pause before
+// the view is re-rendered (--search-debounce).
+var searchDebounce = 150 * time.Millisecond
+
+// searchTerm narrows the view to lines containing it, ignoring case. Guarded
+// by viewMutex.
+var searchTerm string
+
+// startSearch prompts for a search term, narrowing the view as it is typed.
+// Enter keeps the term and Esc restores the previous one.
+func startSearch() {
+	viewMutex.Lock()
+	previous := searchTerm
+	viewMutex.Unlock()
+
+	var timer *time.Timer
+	term, ok := promptWith("/", func(answer string) {
+		viewMutex.Lock()
+		searchTerm = answer
+		viewMutex.Unlock()
+		if searchDebounce <= 0 {
+			reprintLogs()
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(searchDebounce, reprintLogs)
+	})
+	if timer != nil {
+		timer.Stop()
+	}
+
+	viewMutex.Lock()
+	if ok {
+		searchTerm = term
+	} else {
+		searchTerm = previous
+	}
+	viewMutex.Unlock()
+	reprintLogs()
+}
+
+// searchRejects reports whether a line lacks the search term.
+func searchRejects(term, line string) bool {
+	return term != "" && !containsFold(line, term)
+}
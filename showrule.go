@@ -0,0 +1,66 @@
+package main
+
+import "strings"
+
+// showRule appends the highlight rules that matched each line (--show-rule),
+// for tuning overlapping rules.
+var showRule bool
+
+// ruleTags describes the rules matching a line as "[color:word ...]", marking
+// those whose every match lost to an earlier rule as shadowed. It returns an
+// empty line when no rule matches.
+func ruleTags(cfg Config, line string) styledLine {
+	if matchTrimmed {
+		line = trimLine(line)
+	}
+	if matchProjected {
+		line = projectLine(line)
+	}
+
+	var tags []string
+	var kept []span
+	for _, rule := range cfg.Highlights {
+		var spans []span
+		if rule.Groups != nil {
+			spans = groupSpans(rule, line)
+		} else {
+			for _, loc := range rule.findAll(line) {
+				if loc[0] < loc[1] {
+					spans = append(spans, span{loc[0], loc[1], rule.Color})
+				}
+			}
+		}
+		if len(spans) == 0 {
+			continue
+		}
+
+		// Rules take precedence in config order, as in resolveSpans.
+		won := false
+		for _, s := range spans {
+			overlaps := false
+			for _, k := range kept {
+				if s.start < k.end && k.start < s.end {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				kept = append(kept, s)
+				won = true
+			}
+		}
+
+		tag := colorSpecName(rule.Color) + ":" + rule.Word
+		if rule.Groups != nil {
+			tag = "regex:" + rule.Word
+		}
+		if !won {
+			tag += " (shadowed)"
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) == 0 {
+		return styledLine{}
+	}
+	return styledText("["+strings.Join(tags, ", ")+"]", Dim)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var exitMutex sync.Mutex
+var exitHooks []func()
+
+// atExit registers a function to run when loggo shuts down, either at the end
+// of input or on SIGINT/SIGTERM. Hooks run in reverse registration order.
+func atExit(hook func()) {
+	exitMutex.Lock()
+	defer exitMutex.Unlock()
+	exitHooks = append(exitHooks, hook)
+}
+
+// runExitHooks runs and clears all registered exit hooks.
+func runExitHooks() {
+	exitMutex.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitMutex.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// handleSignals runs the exit hooks and exits when loggo is interrupted.
+func handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	runExitHooks()
+	os.Exit(130)
+}
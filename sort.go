@@ -0,0 +1,66 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Options for --sort, which orders the buffer by a key once the input ends.
+var sortField string
+var sortPattern *regexp.Regexp
+var sortDesc bool
+
+// sorted is set once every input has ended and the sorted view is shown.
+// Guarded by logsMutex.
+var sorted bool
+
+// sortKey extracts an entry's sort key: the first capture group (else the
+// whole match) of --sort-regex, or the --sort field of a structured line.
+func sortKey(line string) (string, bool) {
+	return lineKey(line, sortPattern, sortField)
+}
+
+// lessKey compares keys as numbers when both are numeric, else as strings.
+func lessKey(a, b string) bool {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// sortedEntries returns a copy of the entries ordered by their sort keys.
+// Entries without a key go last, and ties keep their arrival order.
+func sortedEntries(entries []LogEntry) []LogEntry {
+	type keyed struct {
+		entry LogEntry
+		key   string
+		ok    bool
+	}
+	items := make([]keyed, len(entries))
+	for i, entry := range entries {
+		key, ok := sortKey(entry.Text)
+		items[i] = keyed{entry, key, ok}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.ok != b.ok {
+			return a.ok
+		}
+		if !a.ok {
+			return false
+		}
+		if sortDesc {
+			return lessKey(b.key, a.key)
+		}
+		return lessKey(a.key, b.key)
+	})
+
+	result := make([]LogEntry, len(items))
+	for i, item := range items {
+		result[i] = item.entry
+	}
+	return result
+}
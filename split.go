@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Split view state, guarded by viewMutex. Each pane shows the buffer with its
+// own filter in place of the config's filter.
+var splitView bool
+var splitFilters [2]string
+
+// toggleSplit asks for two filters and shows the buffer filtered by each side
+// by side, or returns to the normal view.
+func toggleSplit() {
+	viewMutex.Lock()
+	on := splitView
+	viewMutex.Unlock()
+	if on {
+		viewMutex.Lock()
+		splitView = false
+		viewMutex.Unlock()
+		reprintLogs()
+		return
+	}
+
+	left, ok := prompt("Left filter: ")
+	if !ok {
+		return
+	}
+	right, ok := prompt("Right filter: ")
+	if !ok {
+		return
+	}
+	viewMutex.Lock()
+	splitFilters = [2]string{left, right}
+	splitView = true
+	viewMutex.Unlock()
+	reprintLogs()
+}
+
+// paneLines renders the stored lines that pass the config with its filter
+// replaced. The caller must hold logsMutex.
+func paneLines(cfg Config, filter string) []string {
+	cfg.Filter = filter
+	var lines []string
+	for _, log := range storedLogs {
+		keep, text := filterLine(cfg, log.Text)
+		if keep {
+			lines = append(lines, decorate(log, highlightLine(text, cfg)).ansi())
+		}
+	}
+	return lines
+}
+
+// drawSplit renders the newest lines of both panes side by side with a status
+// bar, in place of drawView. The caller must hold logsMutex.
+func drawSplit(w io.Writer) {
+	rows, cols := terminalSize()
+	configMutex.RLock()
+	cfg := currentConfig
+	configMutex.RUnlock()
+
+	viewMutex.Lock()
+	filters := splitFilters
+	prompting := promptLine != "" || statusMessage != ""
+	status := statusText(0)
+	viewMutex.Unlock()
+
+	panes := [2][]string{paneLines(cfg, filters[0]), paneLines(cfg, filters[1])}
+	width := max((cols-3)/2, 1)
+	bodyRows := max(rows-2, 1)
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+	header := [2]string{}
+	for i, filter := range filters {
+		if filter == "" {
+			filter = "(all)"
+		}
+		header[i] = fmt.Sprintf("%q: %d lines", filter, len(panes[i]))
+	}
+	b.WriteString(ClearLine + Bold + padANSI(truncateANSI(header[0], width), width) + " │ " + truncateANSI(header[1], width) + Reset + "\n")
+	for r := 0; r < bodyRows; r++ {
+		var cells [2]string
+		for i, pane := range panes {
+			if j := len(pane) - bodyRows + r; j >= 0 {
+				cells[i] = truncateANSI(pane[j], width)
+			}
+		}
+		b.WriteString(ClearLine + padANSI(cells[0], width) + " │ " + cells[1] + "\n")
+	}
+	if !prompting {
+		status = " split view | v to close"
+	}
+	b.WriteString(ClearLine + Reverse + truncateANSI(fmt.Sprintf("%-*s", cols, status), cols) + Reset)
+	fmt.Fprint(w, b.String())
+}
+
+// padANSI pads a string with spaces to the given visible width.
+func padANSI(s string, width int) string {
+	if n := visibleWidth(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
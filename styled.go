@@ -0,0 +1,193 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// A styledLine is a line of output as text plus the styled ranges of it, so
+// each Formatter renders the styles its own way. Log lines also carry the
+// source and timestamp of their entry.
+//
+// Spans are ordered by start. A span may nest inside an earlier one, as the
+// highlights of a line sit inside its whole-line color, but never straddles
+// the end of one. A span's color is one of the ANSI style codes the config
+// uses, like Red or Bold+Yellow.
+type styledLine struct {
+	text   string
+	spans  []span
+	source string
+	time   time.Time
+}
+
+// plainText returns text without styles.
+func plainText(text string) styledLine {
+	return styledLine{text: text}
+}
+
+// styledText returns text in one style.
+func styledText(text, style string) styledLine {
+	return plainText(text).wrap(style)
+}
+
+// wrap puts the whole line in a style, under its existing spans.
+func (l styledLine) wrap(style string) styledLine {
+	if style == "" || l.text == "" {
+		return l
+	}
+	l.spans = append([]span{{0, len(l.text), style}}, l.spans...)
+	return l
+}
+
+// prepend returns the line after a prefix. The line keeps its metadata.
+func (l styledLine) prepend(prefix styledLine) styledLine {
+	spans := append([]span(nil), prefix.spans...)
+	l.spans = append(spans, offsetSpans(l.spans, len(prefix.text))...)
+	l.text = prefix.text + l.text
+	return l
+}
+
+// append returns the line followed by a suffix.
+func (l styledLine) append(suffix styledLine) styledLine {
+	l.spans = append(append([]span(nil), l.spans...), offsetSpans(suffix.spans, len(l.text))...)
+	l.text += suffix.text
+	return l
+}
+
+// offsetSpans moves spans n bytes later, for text placed after n bytes.
+func offsetSpans(spans []span, n int) []span {
+	moved := make([]span, len(spans))
+	for i, s := range spans {
+		moved[i] = span{s.start + n, s.end + n, s.color}
+	}
+	return moved
+}
+
+// joinStyled joins lines with a plain separator.
+func joinStyled(parts []styledLine, sep string) styledLine {
+	var joined styledLine
+	for i, part := range parts {
+		if i > 0 {
+			joined = joined.append(plainText(sep))
+		}
+		joined = joined.append(part)
+	}
+	return joined
+}
+
+// ansi renders the line with ANSI escapes. Ending a span resets the style and
+// reapplies the spans still open around it, so text after an inner highlight
+// returns to the line's color rather than the default. Blink is dropped
+// unless blinkAllowed.
+func (l styledLine) ansi() string {
+	if len(l.spans) == 0 {
+		return l.text
+	}
+	var b strings.Builder
+	var open []span
+	stripBlink := false
+	for _, s := range l.spans {
+		if strings.Contains(s.color, attributeCodes["blink"]) {
+			stripBlink = !blinkAllowed()
+			break
+		}
+	}
+	writeStyle := func(style string) {
+		if stripBlink {
+			style = withoutBlink(style)
+		}
+		b.WriteString(style)
+	}
+	closeUntil := func(pos int) {
+		for len(open) > 0 && open[len(open)-1].end <= pos {
+			open = open[:len(open)-1]
+			b.WriteString(Reset)
+			for _, s := range open {
+				writeStyle(s.color)
+			}
+		}
+	}
+	last := 0
+	for _, s := range l.spans {
+		if s.start >= s.end {
+			continue
+		}
+		// Write the text up to the span, closing the spans that end on the way.
+		for len(open) > 0 && open[len(open)-1].end <= s.start {
+			end := open[len(open)-1].end
+			b.WriteString(l.text[last:end])
+			last = end
+			closeUntil(end)
+		}
+		b.WriteString(l.text[last:s.start])
+		last = s.start
+		writeStyle(s.color)
+		open = append(open, s)
+	}
+	for len(open) > 0 {
+		end := open[len(open)-1].end
+		b.WriteString(l.text[last:end])
+		last = end
+		closeUntil(end)
+	}
+	b.WriteString(l.text[last:])
+	return b.String()
+}
+
+// segments calls yield for each run of the text between span boundaries,
+// with the colors of the spans around it, outermost first.
+func (l styledLine) segments(yield func(text string, styles []string)) {
+	bounds := []int{0, len(l.text)}
+	for _, s := range l.spans {
+		bounds = append(bounds, s.start, s.end)
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		var styles []string
+		for _, s := range l.spans {
+			if s.start <= start && end <= s.end {
+				styles = append(styles, s.color)
+			}
+		}
+		yield(l.text[start:end], styles)
+	}
+}
+
+// inputStyled turns a line colored by its producer into a styledLine, so
+// --respect-input-color keeps those colors in every format. Escapes other
+// than SGR are dropped.
+func inputStyled(line string) styledLine {
+	var l styledLine
+	var text strings.Builder
+	style := ""
+	start := 0
+	closeRun := func() {
+		if style != "" && text.Len() > start {
+			l.spans = append(l.spans, span{start, text.Len(), style})
+		}
+		start = text.Len()
+	}
+	for i := 0; i < len(line); {
+		n := skipEscape(line[i:])
+		if n == 0 {
+			text.WriteByte(line[i])
+			i++
+			continue
+		}
+		if seq := line[i : i+n]; strings.HasPrefix(seq, "\033[") && strings.HasSuffix(seq, "m") {
+			closeRun()
+			if seq == Reset || seq == "\033[m" {
+				style = ""
+			} else {
+				style += seq
+			}
+		}
+		i += n
+	}
+	closeRun()
+	l.text = text.String()
+	return l
+}
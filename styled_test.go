@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+// TestLineColorRestoredAfterInnerReset checks that a whole-line color comes
+// back after the reset that ends an inner highlight, rather than the rest of
+// the line falling back to the default color.
+func TestLineColorRestoredAfterInnerReset(t *testing.T) {
+	yellowBold := parseColorSpec("yellow+bold")
+	tests := []struct {
+		name string
+		line styledLine
+		want string
+	}{
+		{
+			name: "inner word in the middle",
+			line: styledLine{text: "disk fatal full", spans: []span{{5, 10, yellowBold}}}.wrap(Red),
+			want: Red + "disk " + yellowBold + "fatal" + Reset + Red + " full" + Reset,
+		},
+		{
+			name: "inner word at the end",
+			line: styledLine{text: "disk fatal", spans: []span{{5, 10, yellowBold}}}.wrap(Red),
+			want: Red + "disk " + yellowBold + "fatal" + Reset + Red + Reset,
+		},
+		{
+			name: "two inner words",
+			line: styledLine{text: "a b c", spans: []span{{0, 1, yellowBold}, {2, 3, Blue}}}.wrap(Red),
+			want: Red + yellowBold + "a" + Reset + Red + " " + Blue + "b" + Reset + Red + " c" + Reset,
+		},
+		{
+			name: "no line color leaves the inner reset alone",
+			line: styledLine{text: "disk fatal full", spans: []span{{5, 10, yellowBold}}}.wrap(""),
+			want: "disk " + yellowBold + "fatal" + Reset + " full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.line.ansi(); got != tt.want {
+				t.Errorf("ansi()\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLineColorThroughConfig checks the same through a config whose line rule
+// wraps a bold yellow word rule.
+func TestLineColorThroughConfig(t *testing.T) {
+	cfg := mustParseConfig(t, "disk = red, line\nyellow+bold = fatal\n")
+	got := highlightText("fatal: disk full", cfg)
+	want := Red + parseColorSpec("yellow+bold") + "fatal" + Reset + Red + ": " + Red + "disk" + Reset + Red + " full" + Reset
+	if got != want {
+		t.Errorf("highlightText\n got %q\nwant %q", got, want)
+	}
+}
+
+// TestBlinkKeptInConfig checks that blink survives parsing, so configs and
+// presets written back out keep it, and is only dropped when rendering where it
+// isn't allowed.
+func TestBlinkKeptInConfig(t *testing.T) {
+	saved := noBlink
+	noBlink = true
+	t.Cleanup(func() { noBlink = saved })
+
+	cfg := mustParseConfig(t, "red+blink = FATAL\n")
+	if got, want := formatConfig(cfg), "FATAL = red+blink\n"; got != want {
+		t.Errorf("formatConfig = %q, want %q", got, want)
+	}
+	if got, want := highlightText("FATAL", cfg), Red+"FATAL"+Reset; got != want {
+		t.Errorf("with --no-blink, highlightText = %q, want %q", got, want)
+	}
+}
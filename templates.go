@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Options for --template-group, which shows each line template once with its
+// count. A template is a line with every match of templatePattern, by default
+// each number, replaced by templatePlaceholder.
+var templateGroup bool
+var templatePattern = regexp.MustCompile(`[0-9]+`)
+
+// templatePlaceholder stands for the masked parts of a template.
+const templatePlaceholder = "<N>"
+
+// templateKey returns the template of a line.
+func templateKey(line string) string {
+	return templatePattern.ReplaceAllLiteralString(stripANSI(line), templatePlaceholder)
+}
+
+// templateGroups counts the lines of a render by template, in order of each
+// template's first line.
+type templateGroups struct {
+	order  []string
+	latest map[string]LogEntry
+	counts map[string]int
+}
+
+// add counts a shown entry under its template.
+func (g *templateGroups) add(log LogEntry) {
+	if g.counts == nil {
+		g.latest = make(map[string]LogEntry)
+		g.counts = make(map[string]int)
+	}
+	key := templateKey(log.Text)
+	if g.counts[key] == 0 {
+		g.order = append(g.order, key)
+	}
+	g.counts[key]++
+	g.latest[key] = log
+}
+
+// rows renders one line per template, highlighted and decorated like its
+// latest line, followed by its count.
+func (g *templateGroups) rows(cfg Config) []displayLine {
+	var lines []displayLine
+	for _, key := range g.order {
+		log := g.latest[key]
+		text := decorate(log, highlightLine(key, cfg))
+		if n := g.counts[key]; n > 1 {
+			text = text.append(plainText(" ")).append(styledText(fmt.Sprintf("(x%d)", n), Dim))
+		}
+		lines = append(lines, displayLine{styledLine: text, id: log.ID, isLog: true})
+	}
+	return lines
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Escape sequences for cursor and line control.
+const (
+	HideCursor = "\033[?25l"
+	ShowCursor = "\033[?25h"
+	ClearLine  = "\033[2K"
+	Reverse    = "\033[7m"
+)
+
+// sizeRefreshInterval bounds how long a cached terminal size is trusted.
+const sizeRefreshInterval = time.Second
+
+// tty is the controlling terminal, opened for keyboard input in interactive mode.
+var tty *os.File
+
+var sizeMutex sync.Mutex
+var cachedRows, cachedCols int
+var sizeCheckedAt time.Time
+
+// stty runs stty against the controlling terminal and returns its output. It
+// opens the terminal separately rather than sharing tty, since handing a file
+// to a subprocess puts it in blocking mode and disables read deadlines.
+func stty(args ...string) (string, error) {
+	f, err := os.Open("/dev/tty")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// isTerminal reports whether the file is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSize returns the terminal's rows and columns, falling back to 24x80.
+func terminalSize() (int, int) {
+	sizeMutex.Lock()
+	defer sizeMutex.Unlock()
+
+	if time.Since(sizeCheckedAt) < sizeRefreshInterval {
+		return cachedRows, cachedCols
+	}
+	sizeCheckedAt = time.Now()
+	cachedRows, cachedCols = 24, 80
+
+	out, err := stty("size")
+	if err != nil {
+		return cachedRows, cachedCols
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 2 {
+		rows, errRows := strconv.Atoi(fields[0])
+		cols, errCols := strconv.Atoi(fields[1])
+		if errRows == nil && errCols == nil && rows > 0 && cols > 0 {
+			cachedRows, cachedCols = rows, cols
+		}
+	}
+	return cachedRows, cachedCols
+}
+
+// skipEscape returns the length of the ANSI escape sequence at the start of s,
+// or 0 if s doesn't start with one.
+func skipEscape(s string) int {
+	if len(s) < 2 || s[0] != '\033' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameters, then a final byte in 0x40-0x7e.
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		// OSC: terminated by BEL or ST.
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\033' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+	default:
+		return 2
+	}
+	return len(s)
+}
+
+// stripANSI removes ANSI escape sequences from a string.
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\033") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if n := skipEscape(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// visibleWidth counts the runes of a string that aren't part of escape sequences.
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(stripANSI(s))
+}
+
+// truncateANSI cuts a string to at most width visible runes, keeping escape
+// sequences intact and resetting colors if anything was cut.
+func truncateANSI(s string, width int) string {
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(s); {
+		if n := skipEscape(s[i:]); n > 0 {
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		if visible == width {
+			b.WriteString(Reset)
+			return b.String()
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+		visible++
+	}
+	return b.String()
+}
+
+// clearSequence is written before each full reprint (--clear-seq).
+var clearSequence = ClearScreen
+
+// clearPresets are the named choices for --clear-seq.
+var clearPresets = map[string]string{
+	"ansi":             ClearScreen,
+	"scrollback":       ClearScreen + "\033[3J",
+	"cursor-home-only": "\033[H",
+	"none":             "",
+}
+
+// parseClearSequence resolves a --clear-seq preset name or a literal sequence
+// written with Go escapes such as \033 or \x1b.
+func parseClearSequence(value string) (string, error) {
+	if seq, ok := clearPresets[value]; ok {
+		return seq, nil
+	}
+	return strconv.Unquote(`"` + strings.ReplaceAll(value, `"`, `\"`) + `"`)
+}
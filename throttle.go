@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// maxCPU is the share of one core, in percent, that rendering new lines may
+// use (--max-cpu); 0 renders after every line. Under the cap, renders are
+// coalesced: after each one, loggo waits long enough that rendering stays
+// within the budget, so a busy stream is drawn less often rather than slower.
+var maxCPU float64
+
+// renderRequests holds at most one pending render of new lines.
+var renderRequests = make(chan struct{}, 1)
+
+// renderInterval is the current time between throttled renders, in
+// nanoseconds, for the status bar.
+var renderInterval atomic.Int64
+
+// requestReprint reprints after a new line, directly or through the
+// throttled render loop.
+func requestReprint() {
+	if maxCPU <= 0 {
+		reprintLogs()
+		return
+	}
+	select {
+	case renderRequests <- struct{}{}:
+	default:
+	}
+}
+
+// renderThrottled serves render requests within the --max-cpu budget.
+func renderThrottled() {
+	for range renderRequests {
+		start := time.Now()
+		reprintLogs()
+		took := time.Since(start)
+		pause := time.Duration(float64(took) * (100/maxCPU - 1))
+		renderInterval.Store(int64(took + pause))
+		time.Sleep(pause)
+	}
+}
+
+// renderRate describes the current throttled render rate, or "" when
+// rendering isn't throttled.
+func renderRate() string {
+	interval := renderInterval.Load()
+	if maxCPU <= 0 || interval == 0 {
+		return ""
+	}
+	return fmt.Sprintf("≤%.0f renders/s", float64(time.Second)/float64(interval))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Options for --time-merge.
+var timeMerge bool
+var reorderWindow int
+
+// Timestamp shapes recognized anywhere in a line.
+var (
+	isoTimestamp    = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	clfTimestamp    = regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`)
+	syslogTimestamp = regexp.MustCompile(`(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec) [ \d]\d \d{2}:\d{2}:\d{2}`)
+)
+
+// isoLayouts are tried in order against a normalized ISO 8601 timestamp.
+var isoLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// parseTimestamp finds and parses the first timestamp in a log line.
+func parseTimestamp(line string) (time.Time, bool) {
+	if match := isoTimestamp.FindString(line); match != "" {
+		match = strings.Replace(match, " ", "T", 1)
+		match = strings.Replace(match, ",", ".", 1)
+		for _, layout := range isoLayouts {
+			if t, err := time.ParseInLocation(layout, match, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	if match := clfTimestamp.FindString(line); match != "" {
+		if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", match); err == nil {
+			return t, true
+		}
+	}
+
+	if match := syslogTimestamp.FindString(line); match != "" {
+		if t, err := time.ParseInLocation(time.Stamp, match, time.Local); err == nil {
+			// Syslog omits the year; assume the current one.
+			return t.AddDate(time.Now().Year(), 0, 0), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// insertByTime inserts an entry so timestamped lines stay chronological, moving
+// it back past at most reorderWindow recent lines. Lines without a timestamp
+// keep their arrival order and stay attached to the stamped line before them,
+// so the entry is only ever inserted in front of a later-stamped line.
+func insertByTime(logs []LogEntry, entry LogEntry) []LogEntry {
+	pos := len(logs)
+	for i := len(logs) - 1; i >= 0 && len(logs)-i <= reorderWindow; i-- {
+		if logs[i].Time.IsZero() {
+			continue
+		}
+		if !logs[i].Time.After(entry.Time) {
+			break
+		}
+		pos = i
+	}
+
+	logs = append(logs, LogEntry{})
+	copy(logs[pos+1:], logs[pos:])
+	logs[pos] = entry
+	return logs
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Options for --top, which replaces the view with the most frequent values of
+// a key among the lines that pass the filters, like sort | uniq -c | sort -rn.
+var topField string
+var topPattern *regexp.Regexp
+var topN = 10
+
+// topCounts counts lines by key since the start. Guarded by logsMutex.
+var topCounts = make(map[string]int)
+var topTotal int
+
+// topActive reports whether the view shows the ranking instead of the lines.
+func topActive() bool {
+	return topField != "" || topPattern != nil
+}
+
+// topKey extracts the key a line is counted under: the --top field, or the
+// first capture group (or whole match) of --top-regex.
+func topKey(line string) (string, bool) {
+	return lineKey(line, topPattern, topField)
+}
+
+// countTop counts a new line if it passes the filters and has a key. The
+// caller must hold logsMutex for writing.
+func countTop(cfg Config, line string) {
+	if keep, _ := filterLine(cfg, line); !keep {
+		return
+	}
+	if key, ok := topKey(line); ok {
+		topCounts[key]++
+		topTotal++
+	}
+}
+
+// topLines renders the ranking: a header and the topN keys with their counts,
+// most frequent first. The caller must hold logsMutex.
+func topLines() []displayLine {
+	keys := make([]string, 0, len(topCounts))
+	for key := range topCounts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if topCounts[keys[i]] != topCounts[keys[j]] {
+			return topCounts[keys[i]] > topCounts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	by := topField
+	if topPattern != nil {
+		by = topPattern.String()
+	}
+	header := styledText(fmt.Sprintf("top %d by %s", topN, by), Bold).append(plainText(fmt.Sprintf(" (%d keys, %d lines)", len(keys), topTotal)))
+	lines := []displayLine{{styledLine: header}}
+	for _, key := range keys[:min(topN, len(keys))] {
+		lines = append(lines, displayLine{styledLine: plainText(fmt.Sprintf("%8d  %s", topCounts[key], key))})
+	}
+	return lines
+}
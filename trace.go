@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+)
+
+// Options for --trace-view.
+var traceView bool
+var traceFields = "trace_id,span_id,parent_span_id"
+
+// renderedEntry is a log entry that passed the filters, with its formatted text.
+type renderedEntry struct {
+	log  LogEntry
+	text styledLine
+}
+
+// traceSpan collects the lines logged under one span.
+type traceSpan struct {
+	id       string
+	parent   string
+	lines    []renderedEntry
+	children []string
+}
+
+// traceGroup is one trace with its spans in order of first appearance.
+type traceGroup struct {
+	id    string
+	spans map[string]*traceSpan
+	order []string
+	lines []renderedEntry // Lines with the trace ID but no span ID
+}
+
+// traceTree renders entries grouped by trace and indented by span depth. Spans
+// whose parent isn't in the stream are shown at the root of their trace, and
+// lines without a trace ID are listed last.
+func traceTree(entries []renderedEntry) []displayLine {
+	names := strings.Split(traceFields, ",")
+	for len(names) < 3 {
+		names = append(names, "")
+	}
+	traceField, spanField, parentField := names[0], names[1], names[2]
+
+	groups := make(map[string]*traceGroup)
+	var traceOrder []string
+	var untraced []renderedEntry
+	for _, entry := range entries {
+		fields := parseFields(entry.log.Text)
+		traceID := fields[traceField]
+		if traceID == "" {
+			untraced = append(untraced, entry)
+			continue
+		}
+		group, ok := groups[traceID]
+		if !ok {
+			group = &traceGroup{id: traceID, spans: make(map[string]*traceSpan)}
+			groups[traceID] = group
+			traceOrder = append(traceOrder, traceID)
+		}
+		spanID := fields[spanField]
+		if spanID == "" {
+			group.lines = append(group.lines, entry)
+			continue
+		}
+		span, ok := group.spans[spanID]
+		if !ok {
+			span = &traceSpan{id: spanID}
+			group.spans[spanID] = span
+			group.order = append(group.order, spanID)
+		}
+		if span.parent == "" {
+			span.parent = fields[parentField]
+		}
+		span.lines = append(span.lines, entry)
+	}
+
+	var lines []displayLine
+	emit := func(entry renderedEntry, depth int) {
+		text := decorate(entry.log, entry.text).prepend(plainText(strings.Repeat("  ", depth)))
+		lines = append(lines, displayLine{styledLine: text, id: entry.log.ID, isLog: true})
+	}
+
+	for _, traceID := range traceOrder {
+		group := groups[traceID]
+		lines = append(lines, displayLine{styledLine: styledText("trace "+traceID, Bold)})
+		for _, entry := range group.lines {
+			emit(entry, 1)
+		}
+
+		var roots []string
+		for _, spanID := range group.order {
+			span := group.spans[spanID]
+			if parent, ok := group.spans[span.parent]; ok && span.parent != spanID {
+				parent.children = append(parent.children, spanID)
+			} else {
+				roots = append(roots, spanID)
+			}
+		}
+
+		visited := make(map[string]bool)
+		var walk func(spanID string, depth int)
+		walk = func(spanID string, depth int) {
+			if visited[spanID] {
+				return
+			}
+			visited[spanID] = true
+			span := group.spans[spanID]
+			for _, entry := range span.lines {
+				emit(entry, depth)
+			}
+			for _, child := range span.children {
+				walk(child, depth+1)
+			}
+		}
+		for _, root := range roots {
+			walk(root, 1)
+		}
+		// Spans caught in a parent cycle have no root; show them at the top level.
+		for _, spanID := range group.order {
+			walk(spanID, 1)
+		}
+	}
+
+	if len(untraced) > 0 {
+		lines = append(lines, displayLine{styledLine: styledText("no trace", Bold)})
+		for _, entry := range untraced {
+			emit(entry, 1)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,80 @@
+//go:build expr
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// transformScript is the --transform-script file, only present in binaries
+// built with -tags expr.
+var transformScript string
+
+func init() {
+	flag.StringVar(&transformScript, "transform-script", "", "Rewrite or drop input lines with an expr-lang expression read from this file")
+	optionalSetups = append(optionalSetups, setupTransform)
+}
+
+// transformEnv is what a script sees: the line, its JSON or key=value fields,
+// and the input it came from.
+type transformEnv struct {
+	Line   string            `expr:"line"`
+	Fields map[string]string `expr:"fields"`
+	Source string            `expr:"source"`
+}
+
+// transformFailed makes sure a failing script is reported once, not per line.
+var transformFailed sync.Once
+
+// setupTransform compiles the --transform-script file, exiting if it doesn't
+// compile.
+func setupTransform() {
+	if transformScript == "" {
+		return
+	}
+	source, err := os.ReadFile(transformScript)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in --transform-script:", err)
+		os.Exit(2)
+	}
+	program, err := expr.Compile(string(source), expr.Env(transformEnv{}))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error in --transform-script:", err)
+		os.Exit(2)
+	}
+	lineTransform = func(source, line string) (string, bool) {
+		return runTransform(program, source, line)
+	}
+}
+
+// runTransform applies the script to a line. A string result replaces the
+// line, true keeps it, and false or nil drops it. If the script fails or
+// returns anything else, the line is kept as it is.
+func runTransform(program *vm.Program, source, line string) (string, bool) {
+	fields := parseFields(line)
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	result, err := expr.Run(program, transformEnv{Line: line, Fields: fields, Source: source})
+	if err == nil {
+		switch v := result.(type) {
+		case string:
+			return v, true
+		case bool:
+			return line, v
+		case nil:
+			return line, false
+		}
+		err = fmt.Errorf("the script returned %T, not a string, bool, or nil", result)
+	}
+	transformFailed.Do(func() {
+		fmt.Fprintln(os.Stderr, "Error in --transform-script, keeping lines unchanged:", err)
+	})
+	return line, true
+}
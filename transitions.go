@@ -0,0 +1,12 @@
+package main
+
+import "strings"
+
+// onChange shows a line only when its set of matched highlight keywords
+// differs from the previously shown line's (--on-change).
+var onChange bool
+
+// matchSet identifies the highlight keywords a line matches.
+func matchSet(cfg Config, line string) string {
+	return strings.Join(matchedRules(cfg, line), "\x00")
+}
@@ -0,0 +1,41 @@
+package main
+
+import "regexp"
+
+// Options for trimming redundant prefixes from displayed lines.
+var trimPrefix string
+var trimPrefixPattern *regexp.Regexp
+var matchTrimmed bool // Filter and highlight the trimmed line instead of the full one
+
+// prefixLength returns how many leading bytes of a line to hide: the literal
+// --trim-prefix if the line starts with it, else the --trim-prefix-regex match
+// at the start of the line.
+func prefixLength(line string) int {
+	if trimPrefix != "" && len(line) >= len(trimPrefix) && line[:len(trimPrefix)] == trimPrefix {
+		return len(trimPrefix)
+	}
+	if trimPrefixPattern != nil {
+		if loc := trimPrefixPattern.FindStringIndex(line); loc != nil && loc[0] == 0 {
+			return loc[1]
+		}
+	}
+	return 0
+}
+
+// trimLine removes the configured prefix from a line.
+func trimLine(line string) string {
+	return line[prefixLength(line):]
+}
+
+// shiftSpans moves spans left by n bytes, clipping those that reach into the
+// removed prefix.
+func shiftSpans(spans []span, n int) []span {
+	var shifted []span
+	for _, s := range spans {
+		if s.end <= n {
+			continue
+		}
+		shifted = append(shifted, span{max(s.start, n) - n, s.end - n, s.color})
+	}
+	return shifted
+}
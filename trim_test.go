@@ -0,0 +1,51 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+// TestMatchTrimmedDropsPrefixOnce checks that --match-trimmed hides the prefix
+// once, as without it, for kept lines and for lines --focus dims.
+func TestMatchTrimmedDropsPrefixOnce(t *testing.T) {
+	savedPattern, savedTrimmed, savedFocus := trimPrefixPattern, matchTrimmed, focusMode
+	trimPrefixPattern = regexp.MustCompile(`\S+ `)
+	configMutex.RLock()
+	savedConfig := currentConfig
+	configMutex.RUnlock()
+	t.Cleanup(func() {
+		trimPrefixPattern, matchTrimmed, focusMode = savedPattern, savedTrimmed, savedFocus
+		configMutex.Lock()
+		currentConfig = savedConfig
+		configMutex.Unlock()
+	})
+
+	tests := []struct {
+		name    string
+		config  string
+		trimmed bool
+		focus   bool
+		want    string
+	}{
+		{"kept", "", false, false, "pod-b message"},
+		{"kept, matching trimmed", "", true, false, "pod-b message"},
+		{"dimmed by focus", "filter = nothing\n", false, true, Dim + Gray + "pod-b message" + Reset},
+		{"dimmed by focus, matching trimmed", "filter = nothing\n", true, true, Dim + Gray + "pod-b message" + Reset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchTrimmed, focusMode = tt.trimmed, tt.focus
+			configMutex.Lock()
+			currentConfig = mustParseConfig(t, tt.config)
+			configMutex.Unlock()
+
+			got, ok := filterAndHighlight("pod-a pod-b message")
+			if !ok {
+				t.Fatal("line was dropped")
+			}
+			if got.ansi() != tt.want {
+				t.Errorf("got %q, want %q", got.ansi(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smartUnits enables automatic coloring of sizes and durations (--smart-units).
+var smartUnits bool
+
+// Quantities must not be glued to a preceding word, number, or escape sequence,
+// so identifiers like "v1.2s" and "\033[31m" codes in the input are left alone.
+var (
+	sizePattern     = regexp.MustCompile(`(^|[^\w.\[;])(\d+(?:\.\d+)?) ?((?i:[kmgtp]i?b)|B|bytes)\b`)
+	durationPattern = regexp.MustCompile(`(^|[^\w.\[;])((?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))+)\b`)
+)
+
+// sizeMultipliers maps lowercased size suffixes to their size in bytes.
+var sizeMultipliers = map[string]float64{
+	"b": 1, "bytes": 1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "pb": 1e15,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40, "pib": 1 << 50,
+}
+
+// sizeColor picks a color for a size in bytes, warmer for bigger values.
+func sizeColor(bytes float64) string {
+	switch {
+	case bytes < 1e6:
+		return Green
+	case bytes < 1e9:
+		return Yellow
+	default:
+		return Red
+	}
+}
+
+// durationColor picks a color for a duration, warmer for slower values.
+func durationColor(d time.Duration) string {
+	switch {
+	case d < 100*time.Millisecond:
+		return Green
+	case d < time.Second:
+		return Yellow
+	default:
+		return Red
+	}
+}
+
+// unitSpans finds sizes like "512MB" and durations like "204ms" and colors
+// them by magnitude.
+func unitSpans(line string) []span {
+	var spans []span
+	for _, m := range sizePattern.FindAllStringSubmatchIndex(line, -1) {
+		value, err := strconv.ParseFloat(line[m[4]:m[5]], 64)
+		if err != nil {
+			continue
+		}
+		unit := strings.ToLower(line[m[6]:m[7]])
+		spans = append(spans, span{m[4], m[1], sizeColor(value * sizeMultipliers[unit])})
+	}
+	for _, m := range durationPattern.FindAllStringSubmatchIndex(line, -1) {
+		d, err := time.ParseDuration(line[m[4]:m[5]])
+		if err != nil {
+			continue
+		}
+		spans = append(spans, span{m[4], m[5], durationColor(d)})
+	}
+	return spans
+}
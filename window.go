@@ -0,0 +1,58 @@
+package main
+
+import "strings"
+
+// windowMatch runs the highlight rules over the last N lines joined by
+// newlines, marking lines that a match spans (--window-match). Every new line
+// rescans the window with every rule, so this costs about N times as much as
+// normal highlighting; use regex rules with \s to match across line breaks.
+var windowMatch int
+
+// windowMarker is prepended, in the rule's color, to lines of a cross-line match.
+const windowMarker = "┃"
+
+// markWindowMatches finds rule matches that span lines and end on the newest
+// stored line, and marks every line they cover. The caller must hold
+// logsMutex for writing.
+func markWindowMatches(cfg Config) {
+	if windowMatch < 2 || len(storedLogs) < 2 {
+		return
+	}
+	first := max(len(storedLogs)-windowMatch, 0)
+	window := storedLogs[first:]
+
+	texts := make([]string, len(window))
+	starts := make([]int, len(window)) // Offset of each line in the joined text
+	offset := 0
+	for i, entry := range window {
+		texts[i] = entry.Text
+		starts[i] = offset
+		offset += len(entry.Text) + 1
+	}
+	joined := strings.Join(texts, "\n")
+	last := len(window) - 1
+
+	lineAt := func(pos int) int {
+		line := 0
+		for line < last && starts[line+1] <= pos {
+			line++
+		}
+		return line
+	}
+	for _, rule := range cfg.Highlights {
+		for _, loc := range rule.findAll(joined) {
+			if loc[1] <= starts[last] || loc[0] == loc[1] {
+				continue // Found already, when its last line was the newest
+			}
+			from := lineAt(loc[0])
+			if from == last {
+				continue // Within one line, which normal highlighting covers
+			}
+			for i := from; i <= last; i++ {
+				if window[i].Group == "" {
+					window[i].Group = rule.Color
+				}
+			}
+		}
+	}
+}